@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Formats of the access log
+const (
+	AccessLogLogfmt   = "logfmt"
+	AccessLogJSON     = "json"
+	AccessLogCombined = "combined"
+)
+
+// accessLogEntry describes a request once its response has been sent.
+type accessLogEntry struct {
+	Time      time.Time
+	RequestID string
+	// RemoteAddr is the client IP, without the port
+	RemoteAddr string
+	Method     string
+	URI        string
+	Proto      string
+	Status     int
+	Bytes      int64
+	Duration   time.Duration
+	// UserID is 0 when the request was not authenticated
+	UserID    int
+	UserAgent string
+	Referer   string
+}
+
+type AccessLogger interface {
+	Log(ctx context.Context, entry accessLogEntry)
+}
+
+// NewAccessLogger returns the access logger writing to out in format, one of
+// logfmt, json or combined (the Apache combined log format).
+func NewAccessLogger(format string, out io.Writer) (AccessLogger, error) {
+	switch format {
+	case AccessLogLogfmt:
+		return &structuredAccessLogger{slog.New(traceLogHandler{slog.NewTextHandler(out, nil)})}, nil
+	case AccessLogJSON:
+		return &structuredAccessLogger{slog.New(traceLogHandler{slog.NewJSONHandler(out, nil)})}, nil
+	case AccessLogCombined:
+		return &combinedAccessLogger{out: out}, nil
+	default:
+		return nil, fmt.Errorf("unknown access log format %q, expected logfmt, json or combined", format)
+	}
+}
+
+type structuredAccessLogger struct {
+	logger *slog.Logger
+}
+
+func (l *structuredAccessLogger) Log(ctx context.Context, entry accessLogEntry) {
+	attrs := []slog.Attr{
+		slog.String("request_id", entry.RequestID),
+		slog.String("remote_addr", entry.RemoteAddr),
+		slog.String("method", entry.Method),
+		slog.String("uri", entry.URI),
+		slog.String("proto", entry.Proto),
+		slog.Int("status", entry.Status),
+		slog.Int64("bytes", entry.Bytes),
+		slog.Float64("duration_ms", float64(entry.Duration.Microseconds())/1000),
+	}
+	if entry.UserID != 0 {
+		attrs = append(attrs, slog.Int("user_id", entry.UserID))
+	}
+	attrs = append(attrs, slog.String("user_agent", entry.UserAgent))
+
+	l.logger.LogAttrs(ctx, slog.LevelInfo, "access", attrs...)
+}
+
+// combinedAccessLogger writes the Apache combined log format, with the user
+// ID in place of the remote user.
+type combinedAccessLogger struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (l *combinedAccessLogger) Log(_ context.Context, entry accessLogEntry) {
+	user := "-"
+	if entry.UserID != 0 {
+		user = strconv.Itoa(entry.UserID)
+	}
+	bytes := "-"
+	if entry.Bytes > 0 {
+		bytes = strconv.FormatInt(entry.Bytes, 10)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fmt.Fprintf(l.out, "%s - %s [%s] %s %d %s %s %s\n",
+		entry.RemoteAddr,
+		user,
+		entry.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		strconv.Quote(entry.Method+" "+entry.URI+" "+entry.Proto),
+		entry.Status,
+		bytes,
+		quoteOrDash(entry.Referer),
+		quoteOrDash(entry.UserAgent),
+	)
+}
+
+func quoteOrDash(s string) string {
+	if s == "" {
+		return `"-"`
+	}
+
+	return strconv.Quote(s)
+}
+
+// Logger writes an access log entry once the rest of the stack has handled
+// the request, so that the entry has the final status and size.
+func Logger(accessLog AccessLogger) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			r, info := withRequestInfo(r)
+
+			start := time.Now()
+			wrappedWritter := newWrapperWritter(w)
+			next.ServeHTTP(wrappedWritter, r)
+
+			reqID := requestID(r)
+			accessLog.Log(r.Context(), accessLogEntry{
+				Time:       start,
+				RequestID:  reqID,
+				RemoteAddr: clientIP(r),
+				Method:     r.Method,
+				URI:        r.RequestURI,
+				Proto:      r.Proto,
+				Status:     wrappedWritter.statusCode,
+				Bytes:      wrappedWritter.bytes,
+				Duration:   time.Since(start),
+				UserID:     info.userID,
+				UserAgent:  r.UserAgent(),
+				Referer:    r.Referer(),
+			})
+		}
+	}
+}
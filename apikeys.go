@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NDOY3M4N/api-calculator/repository"
+)
+
+// API keys look like calc_<prefix>_<secret>: the 16 hex characters prefix
+// identifies the key in the database, the secret is only known to its owner.
+// The prefix is long enough for two keys to practically never share one,
+// since the database rejects a duplicate. The keys created before it grew
+// have a prefix of 8 characters, which is still accepted.
+const (
+	apiKeyScheme             = "calc_"
+	apiKeyPrefixLength       = 16
+	apiKeyLegacyPrefixLength = 8
+	apiKeyHeader             = "X-API-Key"
+	// apiKeyTouchInterval is how stale last_used_at may get before it is
+	// written again, so that a busy key does not update its row on every
+	// request
+	apiKeyTouchInterval = time.Minute
+	// maxVerifiedAPIKeys bounds the keys remembered by verifiedAPIKeys
+	maxVerifiedAPIKeys = 10_000
+)
+
+var ErrInvalidAPIKey = errors.New("invalid or revoked api key")
+
+// GenerateAPIKey returns a new key along with its prefix and the hash under
+// which it should be stored.
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	b := make([]byte, apiKeyPrefixLength/2)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", fmt.Errorf("error generating api key: %s", err)
+	}
+	prefix = hex.EncodeToString(b)
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", fmt.Errorf("error generating api key: %s", err)
+	}
+
+	key = apiKeyScheme + prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	return key, prefix, hashAPIKey(key), nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func parseAPIKeyPrefix(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, apiKeyScheme)
+	if !ok {
+		return "", false
+	}
+
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || (len(prefix) != apiKeyPrefixLength && len(prefix) != apiKeyLegacyPrefixLength) || secret == "" {
+		return "", false
+	}
+
+	return prefix, true
+}
+
+// authenticateAPIKey resolves key to its non-revoked record. The hashes are
+// compared in constant time. The keys it accepts are remembered by
+// verifiedAPIKeys.
+func authenticateAPIKey(ctx context.Context, repo repository.Store, key string) (*repository.APIKey, error) {
+	prefix, ok := parseAPIKeyPrefix(key)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := repo.FindAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			verifiedAPIKeys.forget(prefix)
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(hashAPIKey(key))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if apiKey.RevokedAt != nil {
+		verifiedAPIKeys.forget(prefix)
+		return nil, ErrInvalidAPIKey
+	}
+
+	verifiedAPIKeys.add(apiKey)
+
+	return apiKey, nil
+}
+
+// verifiedAPIKeys lets rateLimitKey tell the keys already verified against
+// the database from forged ones, without a query per request.
+var verifiedAPIKeys = newAPIKeyCache(maxVerifiedAPIKeys)
+
+// apiKeyCache maps the prefix of the verified keys to their hash and owner.
+// It only decides which bucket of the rate limiter a request draws from, the
+// keys are still checked against the database by IsAuthenticated.
+type apiKeyCache struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[string]verifiedAPIKey
+}
+
+type verifiedAPIKey struct {
+	id     int64
+	userID int64
+	hash   string
+}
+
+func newAPIKeyCache(size int) *apiKeyCache {
+	return &apiKeyCache{size: size, entries: make(map[string]verifiedAPIKey)}
+}
+
+func (c *apiKeyCache) add(apiKey *repository.APIKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[apiKey.Prefix]; !ok && len(c.entries) >= c.size {
+		// Any entry makes room, an evicted key is verified again on its next
+		// request
+		for prefix := range c.entries {
+			delete(c.entries, prefix)
+			break
+		}
+	}
+	c.entries[apiKey.Prefix] = verifiedAPIKey{id: apiKey.Id, userID: apiKey.UserId, hash: apiKey.KeyHash}
+}
+
+// userID returns the owner of key when it was verified.
+func (c *apiKeyCache) userID(key string) (int64, bool) {
+	prefix, ok := parseAPIKeyPrefix(key)
+	if !ok {
+		return 0, false
+	}
+
+	c.mu.Lock()
+	verified, ok := c.entries[prefix]
+	c.mu.Unlock()
+
+	if !ok || subtle.ConstantTimeCompare([]byte(verified.hash), []byte(hashAPIKey(key))) != 1 {
+		return 0, false
+	}
+
+	return verified.userID, true
+}
+
+func (c *apiKeyCache) forget(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, prefix)
+}
+
+// forgetID drops the key id, once it is revoked.
+func (c *apiKeyCache) forgetID(id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for prefix, verified := range c.entries {
+		if verified.id == id {
+			delete(c.entries, prefix)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Package complexmath implements the complex arithmetic of the calculator on
+// complex128: the four operations, magnitude, phase and conjugate, the polar
+// and rectangular forms, powers and roots.
+//
+// Invalid inputs are reported as an *Error, whose Code tells clients why
+// without parsing the message. Angles are in radians or in degrees, as for
+// the trigonometric functions of the scientific package.
+package complexmath
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	"github.com/NDOY3M4N/api-calculator/scientific"
+)
+
+// Codes of the errors.
+const (
+	// CodeInvalidNumber is a text that is not a complex number in the a+bi
+	// form
+	CodeInvalidNumber = "invalid_number"
+	// CodeZeroDivision is a division by zero, or zero raised to a power whose
+	// real part is not positive
+	CodeZeroDivision = "zero_division"
+	// CodeInvalidDegree is a root of degree zero
+	CodeInvalidDegree = "invalid_degree"
+	// CodeNotFinite is a result too large for complex128
+	CodeNotFinite = "not_finite"
+)
+
+// Error is returned when the inputs of an operation are invalid.
+type Error struct {
+	Code      string `json:"-"`
+	Operation string `json:"operation,omitempty"`
+	Reason    string `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Operation == "" {
+		return e.Reason
+	}
+
+	return fmt.Sprintf("%s: %s", e.Operation, e.Reason)
+}
+
+func errorf(code, operation, format string, args ...any) error {
+	return &Error{Code: code, Operation: operation, Reason: fmt.Sprintf(format, args...)}
+}
+
+func finite(operation string, z complex128) (complex128, error) {
+	if cmplx.IsInf(z) || cmplx.IsNaN(z) {
+		return 0, errorf(CodeNotFinite, operation, "result is not a finite number")
+	}
+
+	return z, nil
+}
+
+// Polar is a complex number given by its modulus and the angle of its phase.
+type Polar struct {
+	Modulus float64 `json:"modulus" example:"5"`
+	Phase   float64 `json:"phase" example:"0.9272952180016122"`
+}
+
+func Add(a, b complex128) (complex128, error) {
+	return finite("add", a+b)
+}
+
+func Sub(a, b complex128) (complex128, error) {
+	return finite("substract", a-b)
+}
+
+func Mul(a, b complex128) (complex128, error) {
+	return finite("multiply", a*b)
+}
+
+// Div returns a divided by b. Go divides complex numbers with Smith's
+// algorithm, which does not overflow on the squares of b's parts.
+func Div(a, b complex128) (complex128, error) {
+	if b == 0 {
+		return 0, errorf(CodeZeroDivision, "divide", "division by zero is prohibited")
+	}
+
+	return finite("divide", a/b)
+}
+
+// Magnitude returns the modulus of z, computed with math.Hypot so that the
+// squares of its parts do not overflow.
+func Magnitude(z complex128) (float64, error) {
+	result := cmplx.Abs(z)
+	if math.IsInf(result, 0) {
+		return 0, errorf(CodeNotFinite, "magnitude", "result is not a finite number")
+	}
+
+	return result, nil
+}
+
+// Phase returns the angle of z in unit, between -π and π, 0 for zero.
+func Phase(z complex128, unit scientific.Unit) float64 {
+	phase := cmplx.Phase(z)
+	if unit == scientific.Degrees {
+		return phase * 180 / math.Pi
+	}
+
+	return phase
+}
+
+func Conjugate(z complex128) complex128 {
+	return cmplx.Conj(z)
+}
+
+// ToPolar returns the modulus and the phase of z.
+func ToPolar(z complex128, unit scientific.Unit) (Polar, error) {
+	modulus, err := Magnitude(z)
+	if err != nil {
+		return Polar{}, errorf(CodeNotFinite, "polar", "result is not a finite number")
+	}
+
+	return Polar{modulus, Phase(z, unit)}, nil
+}
+
+// FromPolar returns the complex number of the given modulus and phase. In
+// degrees, the multiples of 90 are exact, so that a phase of 90 gives 5i
+// rather than 3e-16+5i.
+func FromPolar(p Polar, unit scientific.Unit) (complex128, error) {
+	// The trigonometric functions only fail on the tangent
+	cos, _ := scientific.Cos(p.Phase, unit)
+	sin, _ := scientific.Sin(p.Phase, unit)
+
+	return finite("rectangular", complex(p.Modulus*cos, p.Modulus*sin))
+}
+
+// Pow returns base raised to exponent, on the principal branch of the
+// logarithm. Integer exponents are computed by repeated multiplication, so
+// that i² is exactly -1.
+func Pow(base, exponent complex128) (complex128, error) {
+	if base == 0 {
+		switch {
+		case exponent == 0:
+			return 1, nil
+		case real(exponent) > 0:
+			return 0, nil
+		default:
+			return 0, errorf(CodeZeroDivision, "pow", "zero cannot be raised to a power whose real part is not positive")
+		}
+	}
+
+	if n := real(exponent); imag(exponent) == 0 && n == math.Trunc(n) && math.Abs(n) <= 1<<53 {
+		return finite("pow", intPow(base, int64(n)))
+	}
+
+	return finite("pow", cmplx.Pow(base, exponent))
+}
+
+// intPow raises z to n by squaring.
+func intPow(z complex128, n int64) complex128 {
+	// Inverted first, so that a large power does not overflow before being
+	// inverted
+	if n < 0 {
+		z, n = 1/z, -n
+	}
+
+	result := complex128(1)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			result *= z
+		}
+		z *= z
+	}
+
+	return result
+}
+
+// Root returns the principal root of degree n of z, the one whose phase is
+// the phase of z divided by n.
+func Root(z complex128, n int) (complex128, error) {
+	switch {
+	case n == 0:
+		return 0, errorf(CodeInvalidDegree, "root", "the degree of a root should not be zero")
+	case z == 0 && n < 0:
+		return 0, errorf(CodeZeroDivision, "root", "zero has no root of negative degree")
+	case z == 0:
+		return 0, nil
+	case n == 2:
+		// Exact on perfect squares, e.g. the square root of -4 is 2i
+		return cmplx.Sqrt(z), nil
+	}
+
+	return finite("root", cmplx.Pow(z, complex(1/float64(n), 0)))
+}
@@ -0,0 +1,194 @@
+package complexmath
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/NDOY3M4N/api-calculator/scientific"
+)
+
+func TestArithmetic(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       func(a, b complex128) (complex128, error)
+		a, b     complex128
+		expected complex128
+	}{
+		{"add", Add, 1 + 2i, 3 - 4i, 4 - 2i},
+		{"substract", Sub, 1 + 2i, 3 - 4i, -2 + 6i},
+		{"multiply", Mul, 1 + 2i, 3 - 4i, 11 + 2i},
+		{"multiply i by i", Mul, 1i, 1i, -1},
+		{"divide", Div, 3 + 4i, 1 - 2i, -1 + 2i},
+		{"divide by a real", Div, 3 + 4i, 2, 1.5 + 2i},
+		// Smith's algorithm does not square the parts of the divisor
+		{"divide large numbers", Div, 1e300 + 1e300i, 1e300 + 1e300i, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.fn(tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("%s(%v, %v) = %v, expected %v", tt.name, tt.a, tt.b, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMagnitudeAndPhase(t *testing.T) {
+	if got, err := Magnitude(3 + 4i); err != nil || got != 5 {
+		t.Errorf("Magnitude(3+4i) = %v, %v, expected 5", got, err)
+	}
+	if got, err := Magnitude(3e300 + 4e300i); err != nil || math.Abs(got-5e300) > 1e286 {
+		t.Errorf("Magnitude(3e300+4e300i) = %v, %v, expected 5e300", got, err)
+	}
+
+	tests := []struct {
+		z        complex128
+		unit     scientific.Unit
+		expected float64
+	}{
+		{1i, scientific.Radians, math.Pi / 2},
+		{-1, scientific.Degrees, 180},
+		{-1i, scientific.Degrees, -90},
+		{1 + 1i, scientific.Degrees, 45},
+		{0, scientific.Degrees, 0},
+	}
+
+	for _, tt := range tests {
+		if got := Phase(tt.z, tt.unit); math.Abs(got-tt.expected) > 1e-12 {
+			t.Errorf("Phase(%v, %s) = %v, expected %v", tt.z, tt.unit, got, tt.expected)
+		}
+	}
+
+	if got := Conjugate(3 + 4i); got != 3-4i {
+		t.Errorf("Conjugate(3+4i) = %v, expected 3-4i", got)
+	}
+}
+
+func TestPolar(t *testing.T) {
+	p, err := ToPolar(3+4i, scientific.Radians)
+	if err != nil || p.Modulus != 5 || math.Abs(p.Phase-math.Atan2(4, 3)) > 1e-15 {
+		t.Errorf("ToPolar(3+4i) = %+v, %v", p, err)
+	}
+
+	// The multiples of 90 degrees are exact
+	tests := []struct {
+		polar    Polar
+		expected complex128
+	}{
+		{Polar{5, 90}, 5i},
+		{Polar{2, 180}, -2},
+		{Polar{2, -90}, -2i},
+		{Polar{3, 360}, 3},
+	}
+
+	for _, tt := range tests {
+		got, err := FromPolar(tt.polar, scientific.Degrees)
+		if err != nil || got != tt.expected {
+			t.Errorf("FromPolar(%+v) = %v, %v, expected exactly %v", tt.polar, got, err, tt.expected)
+		}
+	}
+
+	// A round trip through the polar form
+	z, err := FromPolar(p, scientific.Radians)
+	if err != nil || cmplx.Abs(z-(3+4i)) > 1e-14 {
+		t.Errorf("FromPolar(ToPolar(3+4i)) = %v, %v", z, err)
+	}
+}
+
+func TestPow(t *testing.T) {
+	tests := []struct {
+		base, exponent complex128
+		expected       complex128
+	}{
+		// Integer powers are exact
+		{1i, 2, -1},
+		{1i, 3, -1i},
+		{1i, 4, 1},
+		{1 + 1i, 8, 16},
+		{1 + 1i, -2, -0.5i},
+		{2, -1, 0.5},
+		{3 + 4i, 0, 1},
+		{0, 0, 1},
+		{0, 2 + 1i, 0},
+	}
+
+	for _, tt := range tests {
+		got, err := Pow(tt.base, tt.exponent)
+		if err != nil || got != tt.expected {
+			t.Errorf("Pow(%v, %v) = %v, %v, expected exactly %v", tt.base, tt.exponent, got, err, tt.expected)
+		}
+	}
+
+	// i^i is the real number e^(-π/2)
+	got, err := Pow(1i, 1i)
+	if err != nil || cmplx.Abs(got-complex(math.Exp(-math.Pi/2), 0)) > 1e-15 {
+		t.Errorf("Pow(i, i) = %v, %v, expected %v", got, err, math.Exp(-math.Pi/2))
+	}
+}
+
+func TestRoot(t *testing.T) {
+	tests := []struct {
+		z        complex128
+		n        int
+		expected complex128
+	}{
+		{-4, 2, 2i},
+		{-1, 2, 1i},
+		{3 + 4i, 2, 2 + 1i},
+		// The principal cube root of -8 is not -2
+		{-8, 3, 1 + complex(0, math.Sqrt(3))},
+		{16, 4, 2},
+		{4, -2, 0.5},
+		{0, 3, 0},
+	}
+
+	for _, tt := range tests {
+		got, err := Root(tt.z, tt.n)
+		if err != nil || cmplx.Abs(got-tt.expected) > 1e-14 {
+			t.Errorf("Root(%v, %d) = %v, %v, expected %v", tt.z, tt.n, got, err, tt.expected)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		fn        func() error
+		code      string
+		operation string
+	}{
+		{"divide by zero", func() error { _, err := Div(1+1i, 0); return err }, CodeZeroDivision, "divide"},
+		{"zero to a negative power", func() error { _, err := Pow(0, -1); return err }, CodeZeroDivision, "pow"},
+		{"zero to an imaginary power", func() error { _, err := Pow(0, 1i); return err }, CodeZeroDivision, "pow"},
+		{"root of degree zero", func() error { _, err := Root(8, 0); return err }, CodeInvalidDegree, "root"},
+		{"root of zero of negative degree", func() error { _, err := Root(0, -2); return err }, CodeZeroDivision, "root"},
+		{"add overflow", func() error { _, err := Add(complex(math.MaxFloat64, 0), complex(math.MaxFloat64, 0)); return err }, CodeNotFinite, "add"},
+		{"multiply overflow", func() error { _, err := Mul(1e200+1e200i, 1e200); return err }, CodeNotFinite, "multiply"},
+		{"pow overflow", func() error { _, err := Pow(10, 400); return err }, CodeNotFinite, "pow"},
+		{"magnitude overflow", func() error { _, err := Magnitude(complex(math.MaxFloat64, math.MaxFloat64)); return err }, CodeNotFinite, "magnitude"},
+		{"polar overflow", func() error {
+			_, err := ToPolar(complex(math.MaxFloat64, math.MaxFloat64), scientific.Radians)
+			return err
+		}, CodeNotFinite, "polar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fn()
+
+			var cmplxErr *Error
+			if !errors.As(err, &cmplxErr) {
+				t.Fatalf("got error %v, expected an *Error", err)
+			}
+			if cmplxErr.Code != tt.code || cmplxErr.Operation != tt.operation {
+				t.Errorf("got code %q and operation %q, expected %q and %q", cmplxErr.Code, cmplxErr.Operation, tt.code, tt.operation)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package complexmath
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var realPattern = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+
+// Number is a complex number. In JSON it is either an object of its parts,
+// {"re": 3, "im": 4}, a string in the a+bi form, "3+4i", or a real number.
+type Number struct {
+	Re float64 `json:"re" example:"3"`
+	Im float64 `json:"im" example:"4"`
+}
+
+func NewNumber(z complex128) Number {
+	return Number{real(z), imag(z)}
+}
+
+func (n Number) Complex() complex128 {
+	return complex(n.Re, n.Im)
+}
+
+// String returns n in the a+bi form, e.g. "3-4i". The imaginary part is
+// always written, so that the text of a complex number is never read as a
+// real one.
+func (n Number) String() string {
+	return Format(n.Complex())
+}
+
+func (n *Number) UnmarshalJSON(data []byte) error {
+	switch {
+	case len(data) > 0 && data[0] == '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+
+		z, err := Parse(s)
+		if err != nil {
+			return err
+		}
+		*n = NewNumber(z)
+	case len(data) > 0 && data[0] == '{':
+		// The alias does not have this method, so that it is decoded as a
+		// plain struct
+		type parts Number
+		var p parts
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		*n = Number(p)
+	default:
+		var x float64
+		if err := json.Unmarshal(data, &x); err != nil {
+			return err
+		}
+		*n = Number{Re: x}
+	}
+
+	return nil
+}
+
+// Parse reads a complex number in the a+bi form, such as "3+4i", "-2.5i",
+// "1e3-i" or "7". Spaces are ignored.
+func Parse(s string) (complex128, error) {
+	text := strings.ReplaceAll(s, " ", "")
+	invalid := errorf(CodeInvalidNumber, "", "invalid complex number %q, expected the a+bi form", s)
+
+	if !strings.HasSuffix(text, "i") {
+		re, ok := parseReal(text)
+		if !ok {
+			return 0, invalid
+		}
+		return complex(re, 0), nil
+	}
+
+	// The imaginary part starts at the last sign that is not the one of an
+	// exponent, or at the start of the text when there is no real part
+	text = strings.TrimSuffix(text, "i")
+	split := 0
+	for i := len(text) - 1; i > 0; i-- {
+		if (text[i] == '+' || text[i] == '-') && text[i-1] != 'e' && text[i-1] != 'E' {
+			split = i
+			break
+		}
+	}
+
+	re, ok := 0.0, true
+	if split > 0 {
+		re, ok = parseReal(text[:split])
+	}
+
+	// A bare i stands for 1i
+	imText := text[split:]
+	if imText == "" || imText == "+" || imText == "-" {
+		imText += "1"
+	}
+	im, imOk := parseReal(imText)
+	if !ok || !imOk {
+		return 0, invalid
+	}
+
+	return complex(re, im), nil
+}
+
+// parseReal only accepts decimal numbers, strconv.ParseFloat would also read
+// "Inf", "NaN" and hexadecimal numbers.
+func parseReal(s string) (float64, bool) {
+	if !realPattern.MatchString(s) {
+		return 0, false
+	}
+
+	x, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return x, true
+}
+
+// Format returns z in the a+bi form.
+func Format(z complex128) string {
+	re, im := real(z), imag(z)
+	sign := "+"
+	if math.Signbit(im) && im != 0 {
+		sign = "-"
+	}
+
+	return fmt.Sprintf("%s%s%si", formatReal(re), sign, formatReal(math.Abs(im)))
+}
+
+func formatReal(x float64) string {
+	// -0 is written 0
+	if x == 0 {
+		x = 0
+	}
+
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
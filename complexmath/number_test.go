@@ -0,0 +1,116 @@
+package complexmath
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected complex128
+	}{
+		{"3+4i", 3 + 4i},
+		{"3-4i", 3 - 4i},
+		{"-2.5i", -2.5i},
+		{"i", 1i},
+		{"-i", -1i},
+		{"+i", 1i},
+		{"1-i", 1 - 1i},
+		{"7", 7},
+		{"-0.5", -0.5},
+		{"3 + 4i", 3 + 4i},
+		// The sign of an exponent does not start the imaginary part
+		{"1e-3i", 0.001i},
+		{"1e+3-2E-2i", 1000 - 0.02i},
+		{"2.5e-3-1i", 0.0025 - 1i},
+		{"1e+21+2i", 1e21 + 2i},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q): unexpected error %v", tt.input, err)
+			}
+			if got != tt.expected {
+				t.Errorf("Parse(%q) = %v, expected %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{"", "i+3", "3+", "--3i", "NaN", "Inf", "1e999", "0x10", "3+4j", "(1+2i)", "1+2i+3i", "bogus"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := Parse(input)
+
+			var cmplxErr *Error
+			if !errors.As(err, &cmplxErr) || cmplxErr.Code != CodeInvalidNumber {
+				t.Errorf("Parse(%q): got error %v, expected code %q", input, err, CodeInvalidNumber)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input    complex128
+		expected string
+	}{
+		{3 + 4i, "3+4i"},
+		{3 - 4i, "3-4i"},
+		{-1, "-1+0i"},
+		{complex(0, -0.0), "0+0i"},
+		{complex(-0.0, 2), "0+2i"},
+		{0.1 - 2.5i, "0.1-2.5i"},
+		{1e21 + 1e-7i, "1e+21+1e-07i"},
+	}
+
+	for _, tt := range tests {
+		got := Format(tt.input)
+		if got != tt.expected {
+			t.Errorf("Format(%v) = %s, expected %s", tt.input, got, tt.expected)
+		}
+
+		// The text is read back as the same number
+		if back, err := Parse(got); err != nil || back != tt.input {
+			t.Errorf("Parse(%s) = %v, %v, expected %v", got, back, err, tt.input)
+		}
+	}
+}
+
+func TestNumberJSON(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Number
+	}{
+		{`{"re": 1, "im": -2}`, Number{1, -2}},
+		{`{"im": 3}`, Number{0, 3}},
+		{`"2-3i"`, Number{2, -3}},
+		{`5`, Number{5, 0}},
+		{`-1.5`, Number{-1.5, 0}},
+	}
+
+	for _, tt := range tests {
+		var got Number
+		if err := json.Unmarshal([]byte(tt.input), &got); err != nil {
+			t.Errorf("Unmarshal(%s): unexpected error %v", tt.input, err)
+		} else if got != tt.expected {
+			t.Errorf("Unmarshal(%s) = %v, expected %v", tt.input, got, tt.expected)
+		}
+	}
+
+	for _, input := range []string{`"x"`, `{"re": "a"}`, `[1, 2]`, `true`} {
+		var n Number
+		if err := json.Unmarshal([]byte(input), &n); err == nil {
+			t.Errorf("Unmarshal(%s) = %v, expected an error", input, n)
+		}
+	}
+
+	b, err := json.Marshal(Number{3, -4})
+	if err != nil || string(b) != `{"re":3,"im":-4}` {
+		t.Errorf("Marshal = %s, %v", b, err)
+	}
+}
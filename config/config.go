@@ -0,0 +1,444 @@
+// Package config builds the configuration of the server from, by increasing
+// priority: the defaults, a YAML file, the environment variables and the
+// command-line flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+type Config struct {
+	// Listen is the address the HTTP server listens on, e.g. ":3000"
+	Listen string `yaml:"listen"`
+	// MaxBodySize bounds the size of the request bodies, in bytes
+	MaxBodySize int64     `yaml:"max_body_size"`
+	DB          DB        `yaml:"db"`
+	JWT         JWT       `yaml:"jwt"`
+	RateLimit   RateLimit `yaml:"rate_limit"`
+	Log         Log       `yaml:"log"`
+	CORS        CORS      `yaml:"cors"`
+	Tracing     Tracing   `yaml:"tracing"`
+	Shutdown    Shutdown  `yaml:"shutdown"`
+	Math        Math      `yaml:"math"`
+}
+
+type DB struct {
+	// Driver is sqlite or postgres
+	Driver string `yaml:"driver"`
+	// Path of the SQLite database
+	Path string `yaml:"path"`
+	// DSN of the PostgreSQL database, a postgres:// URL or key=value pairs
+	DSN string `yaml:"dsn"`
+	// AutoMigrate applies the pending migrations at startup. When disabled,
+	// they are applied with the migrate command, and the server refuses to
+	// start while some are pending.
+	AutoMigrate bool `yaml:"auto_migrate"`
+	// QueryTimeout bounds each call to the store, 0 disables it
+	QueryTimeout time.Duration `yaml:"query_timeout"`
+	// BusyTimeout is how long SQLite waits for the lock of another
+	// connection before failing
+	BusyTimeout     time.Duration `yaml:"busy_timeout"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+}
+
+type JWT struct {
+	Secret     string        `yaml:"secret"`
+	Issuer     string        `yaml:"issuer"`
+	AccessTTL  time.Duration `yaml:"access_ttl"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl"`
+}
+
+type RateLimit struct {
+	// Burst is the number of tokens of a full bucket
+	Burst int64 `yaml:"burst"`
+	// Rate is the number of tokens added to a bucket per second
+	Rate    int64         `yaml:"rate"`
+	IdleTTL time.Duration `yaml:"idle_ttl"`
+}
+
+type Log struct {
+	// Level is debug, info, warn or error
+	Level string `yaml:"level"`
+	// Format of the application logs: text, logfmt or json
+	Format string `yaml:"format"`
+	// AccessFormat is the format of the access log: logfmt, json or combined
+	AccessFormat string `yaml:"access_format"`
+}
+
+type CORS struct {
+	// Origins allowed to call the API from a browser, "*" for any. CORS is
+	// disabled when empty.
+	Origins []string `yaml:"origins"`
+}
+
+type Tracing struct {
+	// Exporter is otlp, stdout or none
+	Exporter string `yaml:"exporter"`
+}
+
+type Shutdown struct {
+	// Timeout bounds the time given to in-flight requests
+	Timeout time.Duration `yaml:"timeout"`
+	// DrainDelay is how long /readyz fails before the server stops accepting
+	// connections, for the load balancer to stop sending traffic
+	DrainDelay time.Duration `yaml:"drain_delay"`
+}
+
+type Math struct {
+	// MaxMatrixSize bounds the rows and columns of the matrices, and the
+	// numbers of the vectors, the server computes with
+	MaxMatrixSize int `yaml:"max_matrix_size"`
+}
+
+func Default() Config {
+	return Config{
+		Listen:      ":3000",
+		MaxBodySize: 1 << 20,
+		DB: DB{
+			Driver:          "sqlite",
+			Path:            "database.db",
+			AutoMigrate:     true,
+			QueryTimeout:    5 * time.Second,
+			BusyTimeout:     3 * time.Second,
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+		},
+		JWT: JWT{
+			Secret:     "my-jwt-secret",
+			Issuer:     "api-calculator",
+			AccessTTL:  15 * time.Minute,
+			RefreshTTL: 30 * 24 * time.Hour,
+		},
+		RateLimit: RateLimit{
+			Burst:   5,
+			Rate:    2,
+			IdleTTL: 10 * time.Minute,
+		},
+		Log: Log{
+			Level:        "info",
+			Format:       "text",
+			AccessFormat: "logfmt",
+		},
+		Tracing: Tracing{Exporter: "none"},
+		Shutdown: Shutdown{
+			Timeout: 15 * time.Second,
+		},
+		Math: Math{MaxMatrixSize: 100},
+	}
+}
+
+// Options are the flags that are not part of the configuration.
+type Options struct {
+	// File is the YAML file the configuration was read from, if any
+	File string
+	// PrintConfig asks to print the effective configuration and exit
+	PrintConfig bool
+}
+
+// setting binds a flag to the environment variable that can set it as well.
+type setting struct {
+	flag  string
+	env   string
+	usage string
+}
+
+var settings = []setting{
+	{"listen", "LISTEN_ADDR", "address the HTTP server listens on"},
+	{"max-body-size", "MAX_BODY_SIZE", "maximum size of a request body, in bytes"},
+	{"db-driver", "DB_DRIVER", "database to store the data in: sqlite or postgres"},
+	{"db-path", "DB_PATH", "path of the SQLite database"},
+	{"db-dsn", "DATABASE_URL", "DSN of the PostgreSQL database"},
+	{"db-auto-migrate", "DB_AUTO_MIGRATE", "apply the pending migrations at startup"},
+	{"db-query-timeout", "DB_QUERY_TIMEOUT", "timeout of each call to the database, 0 to disable it"},
+	{"db-busy-timeout", "DB_BUSY_TIMEOUT", "time SQLite waits for a locked database before failing"},
+	{"db-max-open-conns", "DB_MAX_OPEN_CONNS", "maximum number of open connections to the database"},
+	{"db-max-idle-conns", "DB_MAX_IDLE_CONNS", "maximum number of idle connections to the database"},
+	{"db-conn-max-lifetime", "DB_CONN_MAX_LIFETIME", "time after which a connection to the database is closed"},
+	{"jwt-secret", "JWT_SECRET", "secret signing the access tokens"},
+	{"jwt-issuer", "JWT_ISSUER", "issuer of the access tokens"},
+	{"jwt-access-ttl", "JWT_ACCESS_TTL", "lifetime of the access tokens"},
+	{"jwt-refresh-ttl", "JWT_REFRESH_TTL", "lifetime of the refresh tokens"},
+	{"rate-limit-burst", "RATE_LIMIT_BURST", "number of tokens of a full bucket"},
+	{"rate-limit-rate", "RATE_LIMIT_RATE", "number of tokens added to a bucket per second"},
+	{"rate-limit-idle-ttl", "RATE_LIMIT_IDLE_TTL", "time after which an unused bucket is dropped"},
+	{"log-level", "LOG_LEVEL", "minimum level of the logs: debug, info, warn or error"},
+	{"log-format", "LOG_FORMAT", "format of the logs: text, logfmt or json"},
+	{"access-log-format", "ACCESS_LOG_FORMAT", "format of the access log: logfmt, json or combined"},
+	{"cors-origins", "CORS_ORIGINS", "comma-separated origins allowed by CORS, * for any"},
+	{"traces-exporter", "OTEL_TRACES_EXPORTER", "exporter of the spans: otlp, stdout or none"},
+	{"shutdown-timeout", "SHUTDOWN_TIMEOUT", "time given to in-flight requests on shutdown"},
+	{"drain-delay", "DRAIN_DELAY", "time /readyz fails before the server stops accepting connections"},
+	{"max-matrix-size", "MAX_MATRIX_SIZE", "maximum number of rows and columns of a matrix"},
+}
+
+// Load parses args, reads the YAML file given with --config (or the
+// CALC_CONFIG environment variable) and applies the environment variables
+// found with lookupEnv. The result is validated.
+func Load(name string, args []string, lookupEnv func(string) (string, bool)) (Config, Options, error) {
+	var (
+		cfg  = Default()
+		opts Options
+		fs   = flag.NewFlagSet(name, flag.ContinueOnError)
+	)
+
+	fs.StringVar(&opts.File, "config", "", "YAML configuration file (env CALC_CONFIG)")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print the effective configuration, secrets redacted, and exit")
+	cfg.bind(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return cfg, opts, err
+	}
+	if fs.NArg() > 0 {
+		return cfg, opts, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	// The flags are applied again last, so that they win over the file and
+	// the environment
+	explicit := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = f.Value.String()
+	})
+
+	if opts.File == "" {
+		opts.File, _ = lookupEnv("CALC_CONFIG")
+	}
+	if opts.File != "" {
+		if err := cfg.readFile(opts.File); err != nil {
+			return cfg, opts, err
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := lookupEnv(s.env); ok {
+			if err := fs.Set(s.flag, value); err != nil {
+				return cfg, opts, fmt.Errorf("invalid value %q for %s: %w", value, s.env, err)
+			}
+		}
+	}
+
+	for name, value := range explicit {
+		if err := fs.Set(name, value); err != nil {
+			return cfg, opts, err
+		}
+	}
+
+	return cfg, opts, cfg.Validate()
+}
+
+func (c *Config) bind(fs *flag.FlagSet) {
+	usage := func(flag string) string {
+		i := slices.IndexFunc(settings, func(s setting) bool { return s.flag == flag })
+		return fmt.Sprintf("%s (env %s)", settings[i].usage, settings[i].env)
+	}
+
+	fs.StringVar(&c.Listen, "listen", c.Listen, usage("listen"))
+	fs.Int64Var(&c.MaxBodySize, "max-body-size", c.MaxBodySize, usage("max-body-size"))
+	fs.StringVar(&c.DB.Driver, "db-driver", c.DB.Driver, usage("db-driver"))
+	fs.StringVar(&c.DB.Path, "db-path", c.DB.Path, usage("db-path"))
+	fs.StringVar(&c.DB.DSN, "db-dsn", c.DB.DSN, usage("db-dsn"))
+	fs.BoolVar(&c.DB.AutoMigrate, "db-auto-migrate", c.DB.AutoMigrate, usage("db-auto-migrate"))
+	fs.DurationVar(&c.DB.QueryTimeout, "db-query-timeout", c.DB.QueryTimeout, usage("db-query-timeout"))
+	fs.DurationVar(&c.DB.BusyTimeout, "db-busy-timeout", c.DB.BusyTimeout, usage("db-busy-timeout"))
+	fs.IntVar(&c.DB.MaxOpenConns, "db-max-open-conns", c.DB.MaxOpenConns, usage("db-max-open-conns"))
+	fs.IntVar(&c.DB.MaxIdleConns, "db-max-idle-conns", c.DB.MaxIdleConns, usage("db-max-idle-conns"))
+	fs.DurationVar(&c.DB.ConnMaxLifetime, "db-conn-max-lifetime", c.DB.ConnMaxLifetime, usage("db-conn-max-lifetime"))
+	fs.StringVar(&c.JWT.Secret, "jwt-secret", c.JWT.Secret, usage("jwt-secret"))
+	fs.StringVar(&c.JWT.Issuer, "jwt-issuer", c.JWT.Issuer, usage("jwt-issuer"))
+	fs.DurationVar(&c.JWT.AccessTTL, "jwt-access-ttl", c.JWT.AccessTTL, usage("jwt-access-ttl"))
+	fs.DurationVar(&c.JWT.RefreshTTL, "jwt-refresh-ttl", c.JWT.RefreshTTL, usage("jwt-refresh-ttl"))
+	fs.Int64Var(&c.RateLimit.Burst, "rate-limit-burst", c.RateLimit.Burst, usage("rate-limit-burst"))
+	fs.Int64Var(&c.RateLimit.Rate, "rate-limit-rate", c.RateLimit.Rate, usage("rate-limit-rate"))
+	fs.DurationVar(&c.RateLimit.IdleTTL, "rate-limit-idle-ttl", c.RateLimit.IdleTTL, usage("rate-limit-idle-ttl"))
+	fs.StringVar(&c.Log.Level, "log-level", c.Log.Level, usage("log-level"))
+	fs.StringVar(&c.Log.Format, "log-format", c.Log.Format, usage("log-format"))
+	fs.StringVar(&c.Log.AccessFormat, "access-log-format", c.Log.AccessFormat, usage("access-log-format"))
+	fs.Var((*listValue)(&c.CORS.Origins), "cors-origins", usage("cors-origins"))
+	fs.StringVar(&c.Tracing.Exporter, "traces-exporter", c.Tracing.Exporter, usage("traces-exporter"))
+	fs.DurationVar(&c.Shutdown.Timeout, "shutdown-timeout", c.Shutdown.Timeout, usage("shutdown-timeout"))
+	fs.DurationVar(&c.Shutdown.DrainDelay, "drain-delay", c.Shutdown.DrainDelay, usage("drain-delay"))
+	fs.IntVar(&c.Math.MaxMatrixSize, "max-matrix-size", c.Math.MaxMatrixSize, usage("max-matrix-size"))
+}
+
+func (c *Config) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		invalid("listen: %q is not a host:port address", c.Listen)
+	}
+	if c.MaxBodySize < 1024 {
+		invalid("max_body_size: should be at least 1024")
+	}
+	switch c.DB.Driver {
+	case "sqlite":
+		if c.DB.Path == "" {
+			invalid("db.path: should not be empty")
+		}
+	case "postgres":
+		if c.DB.DSN == "" {
+			invalid("db.dsn: should not be empty with the postgres driver")
+		}
+	default:
+		invalid("db.driver: %q should be sqlite or postgres", c.DB.Driver)
+	}
+	if c.DB.QueryTimeout < 0 {
+		invalid("db.query_timeout: should not be negative")
+	}
+	if c.DB.BusyTimeout < 0 {
+		invalid("db.busy_timeout: should not be negative")
+	}
+	if c.DB.MaxOpenConns < 1 {
+		invalid("db.max_open_conns: should be at least 1")
+	}
+	if c.DB.MaxIdleConns < 0 || c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		invalid("db.max_idle_conns: should be between 0 and db.max_open_conns")
+	}
+	if c.DB.ConnMaxLifetime < 0 {
+		invalid("db.conn_max_lifetime: should not be negative")
+	}
+
+	if c.JWT.Secret == "" {
+		invalid("jwt.secret: should not be empty")
+	}
+	if c.JWT.Issuer == "" {
+		invalid("jwt.issuer: should not be empty")
+	}
+	if c.JWT.AccessTTL <= 0 {
+		invalid("jwt.access_ttl: should be positive")
+	}
+	if c.JWT.RefreshTTL <= c.JWT.AccessTTL {
+		invalid("jwt.refresh_ttl: should be longer than jwt.access_ttl")
+	}
+
+	if c.RateLimit.Burst < 1 {
+		invalid("rate_limit.burst: should be at least 1")
+	}
+	if c.RateLimit.Rate < 1 || c.RateLimit.Rate > 1000 {
+		invalid("rate_limit.rate: should be between 1 and 1000")
+	}
+	if c.RateLimit.IdleTTL <= 0 {
+		invalid("rate_limit.idle_ttl: should be positive")
+	}
+
+	if !slices.Contains([]string{"debug", "info", "warn", "error"}, c.Log.Level) {
+		invalid("log.level: %q should be debug, info, warn or error", c.Log.Level)
+	}
+	if !slices.Contains([]string{"text", "logfmt", "json"}, c.Log.Format) {
+		invalid("log.format: %q should be text, logfmt or json", c.Log.Format)
+	}
+	if !slices.Contains([]string{"logfmt", "json", "combined"}, c.Log.AccessFormat) {
+		invalid("log.access_format: %q should be logfmt, json or combined", c.Log.AccessFormat)
+	}
+
+	for _, origin := range c.CORS.Origins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			invalid("cors.origins: %q should be * or a scheme://host[:port] origin", origin)
+		}
+	}
+
+	if !slices.Contains([]string{"otlp", "stdout", "none"}, c.Tracing.Exporter) {
+		invalid("tracing.exporter: %q should be otlp, stdout or none", c.Tracing.Exporter)
+	}
+
+	if c.Shutdown.Timeout <= 0 {
+		invalid("shutdown.timeout: should be positive")
+	}
+	if c.Shutdown.DrainDelay < 0 {
+		invalid("shutdown.drain_delay: should not be negative")
+	}
+
+	if c.Math.MaxMatrixSize < 1 {
+		invalid("math.max_matrix_size: should be at least 1")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Redacted returns a copy of c without its secrets.
+func (c Config) Redacted() Config {
+	if c.JWT.Secret != "" {
+		c.JWT.Secret = redacted
+	}
+	c.DB.DSN = redactDSN(c.DB.DSN)
+	c.CORS.Origins = slices.Clone(c.CORS.Origins)
+
+	return c
+}
+
+var dsnPassword = regexp.MustCompile(`password=\S+`)
+
+// redactDSN hides the password of a URL or key=value DSN.
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		return u.Redacted()
+	}
+
+	return dsnPassword.ReplaceAllString(dsn, "password="+redacted)
+}
+
+// Print writes c as YAML to w, secrets redacted.
+func (c Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	defer enc.Close()
+
+	return enc.Encode(c.Redacted())
+}
+
+// listValue is a comma-separated list flag.
+type listValue []string
+
+func (l *listValue) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+
+	return nil
+}
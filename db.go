@@ -2,10 +2,84 @@ package main
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/NDOY3M4N/api-calculator/config"
+	"github.com/NDOY3M4N/api-calculator/migrations"
+	"github.com/NDOY3M4N/api-calculator/repository"
+	"github.com/NDOY3M4N/api-calculator/repository/postgres"
+	"github.com/NDOY3M4N/api-calculator/repository/sqlite"
 )
 
-func NewDatabaseSqlite(path string) (*sql.DB, error) {
-	return sql.Open("sqlite3", path)
+// NewDatabaseSqlite opens the SQLite database at path in WAL mode, so that
+// readers do not wait for the writer, and makes a connection wait up to
+// busyTimeout for the lock of another one.
+func NewDatabaseSqlite(path string, busyTimeout time.Duration) (*sql.DB, error) {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	dsn := fmt.Sprintf("%s%s_journal_mode=WAL&_busy_timeout=%d", path, sep, busyTimeout.Milliseconds())
+
+	return sql.Open("sqlite3", dsn)
+}
+
+func NewDatabasePostgres(dsn string) (*sql.DB, error) {
+	return sql.Open("pgx", dsn)
+}
+
+// NewDatabase opens the database selected by c.Driver, sizes its connection
+// pool and returns its dialect.
+func NewDatabase(c config.DB) (*sql.DB, migrations.Dialect, error) {
+	var (
+		db      *sql.DB
+		dialect migrations.Dialect
+		err     error
+	)
+	switch c.Driver {
+	case "sqlite":
+		db, err = NewDatabaseSqlite(c.Path, c.BusyTimeout)
+		dialect = migrations.SQLite
+	case "postgres":
+		db, err = NewDatabasePostgres(c.DSN)
+		dialect = migrations.Postgres
+	default:
+		return nil, "", fmt.Errorf("unknown database driver %q", c.Driver)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	db.SetMaxIdleConns(c.MaxIdleConns)
+	db.SetConnMaxLifetime(c.ConnMaxLifetime)
+
+	return db, dialect, nil
+}
+
+// NewStore returns the store of db, a database of dialect d, whose calls
+// are bounded by queryTimeout.
+func NewStore(db *sql.DB, d migrations.Dialect, queryTimeout time.Duration) repository.Store {
+	if d == migrations.Postgres {
+		return postgres.New(db, queryTimeout)
+	}
+
+	return sqlite.New(db, queryTimeout)
+}
+
+// isDatabaseBusy reports whether err comes from a SQLite database locked by
+// another connection for longer than its busy timeout.
+func isDatabaseBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
 }
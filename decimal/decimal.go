@@ -0,0 +1,111 @@
+// Package decimal implements exact decimal arithmetic on top of math/big.
+//
+// Numbers are parsed from their decimal text into big.Rat values so that
+// additions, substractions and multiplications are always exact. Results are
+// formatted back to plain decimal text, either exactly or rounded to a number
+// of significant digits using one of the supported rounding modes. Fractions
+// such as "1/3" are parsed and formatted the same way, for the results that
+// have no finite decimal expansion.
+package decimal
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultPrecision is the number of significant digits used when a result
+	// has no finite decimal expansion (e.g. 1/3) and no precision was
+	// requested. It matches the IEEE 754 decimal128 format.
+	DefaultPrecision = 34
+
+	// MaxPrecision is the largest number of significant digits a caller may
+	// request.
+	MaxPrecision = 1000
+
+	// MaxExponent bounds the exponent accepted in scientific notation so that
+	// a tiny input such as "1e999999999" cannot allocate a huge integer.
+	MaxExponent = 6144
+)
+
+var (
+	ErrInvalidNumber   = errors.New("invalid decimal number")
+	ErrExponentRange   = fmt.Errorf("exponent should be between -%d and %d", MaxExponent, MaxExponent)
+	ErrDivisionByZero  = errors.New("division by zero is prohibited")
+	ErrInvalidRounding = errors.New("invalid rounding mode")
+	ErrPrecisionRange  = fmt.Errorf("precision should be between 0 and %d", MaxPrecision)
+)
+
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE]([+-]?\d+))?$`)
+
+// Parse converts a decimal string such as "-12.345" or "1.5e-3" into an
+// exact rational value.
+func Parse(s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+
+	m := decimalPattern.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidNumber, s)
+	}
+
+	if m[3] != "" {
+		exp, err := strconv.Atoi(m[3])
+		if err != nil || exp < -MaxExponent || exp > MaxExponent {
+			return nil, ErrExponentRange
+		}
+	}
+
+	x, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidNumber, s)
+	}
+
+	return x, nil
+}
+
+// ParseAll parses every string of xs, stopping at the first invalid one.
+func ParseAll(xs []string) ([]*big.Rat, error) {
+	values := make([]*big.Rat, 0, len(xs))
+	for _, s := range xs {
+		x, err := Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, x)
+	}
+
+	return values, nil
+}
+
+func Add(a, b *big.Rat) *big.Rat {
+	return new(big.Rat).Add(a, b)
+}
+
+func Sub(a, b *big.Rat) *big.Rat {
+	return new(big.Rat).Sub(a, b)
+}
+
+func Mul(a, b *big.Rat) *big.Rat {
+	return new(big.Rat).Mul(a, b)
+}
+
+func Quo(a, b *big.Rat) (*big.Rat, error) {
+	if b.Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+
+	return new(big.Rat).Quo(a, b), nil
+}
+
+func Sum(xs []*big.Rat) *big.Rat {
+	result := new(big.Rat)
+	for _, x := range xs {
+		result.Add(result, x)
+	}
+
+	return result
+}
@@ -0,0 +1,114 @@
+package decimal
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func rat(s string) *big.Rat {
+	x, ok := new(big.Rat).SetString(s)
+	if !ok {
+		panic("invalid rational " + s)
+	}
+
+	return x
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0", "0"},
+		{"-12.345", "-2469/200"},
+		{"+7", "7"},
+		{".5", "1/2"},
+		{"5.", "5"},
+		{"1.5e-3", "3/2000"},
+		{"2E3", "2000"},
+		{" 0.1 ", "1/10"},
+		{"1e6144", "1" + strings.Repeat("0", 6144)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q): unexpected error %v", tt.input, err)
+			}
+			if got.Cmp(rat(tt.expected)) != 0 {
+				t.Errorf("Parse(%q) = %s, expected %s", tt.input, got.RatString(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected error
+	}{
+		{"", ErrInvalidNumber},
+		{"abc", ErrInvalidNumber},
+		{"1.2.3", ErrInvalidNumber},
+		{"1/3", ErrInvalidNumber},
+		{"0x10", ErrInvalidNumber},
+		{"Inf", ErrInvalidNumber},
+		{"1e", ErrInvalidNumber},
+		{"1e6145", ErrExponentRange},
+		{"1e-6145", ErrExponentRange},
+		{"1e99999999999999999999", ErrExponentRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if _, err := Parse(tt.input); !errors.Is(err, tt.expected) {
+				t.Errorf("Parse(%q): got error %v, expected %v", tt.input, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	values, err := ParseAll([]string{"0.1", "0.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Sum(values); got.Cmp(rat("3/10")) != 0 {
+		t.Errorf("0.1 + 0.2 = %s, expected 3/10", got.RatString())
+	}
+
+	if _, err := ParseAll([]string{"1", "x", "2"}); !errors.Is(err, ErrInvalidNumber) {
+		t.Errorf("ParseAll: got error %v, expected %v", err, ErrInvalidNumber)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	a, b := rat("1/10"), rat("3")
+
+	for _, tt := range []struct {
+		name     string
+		got      *big.Rat
+		expected string
+	}{
+		{"Add", Add(a, b), "31/10"},
+		{"Sub", Sub(a, b), "-29/10"},
+		{"Mul", Mul(a, b), "3/10"},
+		{"Sum", Sum([]*big.Rat{a, a, a}), "3/10"},
+		{"Sum of nothing", Sum(nil), "0"},
+	} {
+		if tt.got.Cmp(rat(tt.expected)) != 0 {
+			t.Errorf("%s = %s, expected %s", tt.name, tt.got.RatString(), tt.expected)
+		}
+	}
+
+	got, err := Quo(a, b)
+	if err != nil || got.Cmp(rat("1/30")) != 0 {
+		t.Errorf("Quo = %v, %v, expected 1/30", got, err)
+	}
+	if _, err := Quo(a, new(big.Rat)); !errors.Is(err, ErrDivisionByZero) {
+		t.Errorf("Quo by zero: got error %v, expected %v", err, ErrDivisionByZero)
+	}
+}
@@ -0,0 +1,204 @@
+package decimal
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// RoundingMode tells Format how to discard the digits beyond the requested
+// precision.
+type RoundingMode string
+
+const (
+	// HalfEven rounds to the nearest neighbour, ties to the even one
+	// (banker's rounding)
+	HalfEven RoundingMode = "half_even"
+	// HalfUp rounds to the nearest neighbour, ties away from zero
+	HalfUp RoundingMode = "half_up"
+	// HalfDown rounds to the nearest neighbour, ties towards zero
+	HalfDown RoundingMode = "half_down"
+	// Up rounds away from zero
+	Up RoundingMode = "up"
+	// Down rounds towards zero (truncation)
+	Down RoundingMode = "down"
+	// Ceiling rounds towards positive infinity
+	Ceiling RoundingMode = "ceiling"
+	// Floor rounds towards negative infinity
+	Floor RoundingMode = "floor"
+)
+
+// ParseRoundingMode validates s and defaults to HalfEven when it is empty.
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	mode := RoundingMode(strings.ToLower(strings.TrimSpace(s)))
+
+	switch mode {
+	case "":
+		return HalfEven, nil
+	case HalfEven, HalfUp, HalfDown, Up, Down, Ceiling, Floor:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidRounding, s)
+	}
+}
+
+// ValidatePrecision checks that precision is within [0, MaxPrecision].
+func ValidatePrecision(precision int) error {
+	if precision < 0 || precision > MaxPrecision {
+		return ErrPrecisionRange
+	}
+
+	return nil
+}
+
+// Format returns x as plain decimal text.
+//
+// When precision is 0 the exact value is returned if x has a finite decimal
+// expansion, otherwise it is rounded to DefaultPrecision significant digits.
+// When precision is positive, x is rounded to that many significant digits
+// using mode. Trailing zeros after the decimal point are always removed.
+func Format(x *big.Rat, precision int, mode RoundingMode) string {
+	if precision == 0 {
+		if places, ok := exactDecimalPlaces(x.Denom()); ok {
+			n := new(big.Int).Mul(x.Num(), pow10(places))
+			n.Quo(n, x.Denom())
+
+			return formatScaled(n, places)
+		}
+
+		precision = DefaultPrecision
+	}
+
+	return round(x, precision, mode)
+}
+
+// exactDecimalPlaces reports whether 1/den has a finite decimal expansion and,
+// if so, how many digits it needs after the decimal point.
+func exactDecimalPlaces(den *big.Int) (int, bool) {
+	d := new(big.Int).Set(den)
+	two, five := big.NewInt(2), big.NewInt(5)
+	rem := new(big.Int)
+
+	var twos, fives int
+	for {
+		q, r := new(big.Int).QuoRem(d, two, rem)
+		if r.Sign() != 0 {
+			break
+		}
+		d, twos = q, twos+1
+	}
+	for {
+		q, r := new(big.Int).QuoRem(d, five, rem)
+		if r.Sign() != 0 {
+			break
+		}
+		d, fives = q, fives+1
+	}
+
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+
+	return max(twos, fives), true
+}
+
+func round(x *big.Rat, precision int, mode RoundingMode) string {
+	if x.Sign() == 0 {
+		return "0"
+	}
+
+	negative := x.Sign() < 0
+	abs := new(big.Rat).Abs(x)
+
+	// Find e such that 10^e <= abs < 10^(e+1). The digit count difference is
+	// off by at most one, which the comparisons below correct.
+	e := len(abs.Num().String()) - len(abs.Denom().String())
+	if abs.Cmp(ratPow10(e)) < 0 {
+		e--
+	} else if abs.Cmp(ratPow10(e+1)) >= 0 {
+		e++
+	}
+
+	// Scale abs so that its integer part holds exactly `precision` digits
+	scale := precision - 1 - e
+	scaled := new(big.Rat).Mul(abs, ratPow10(scale))
+
+	n, rem := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if rem.Sign() != 0 && roundsAway(n, rem, scaled.Denom(), negative, mode) {
+		n.Add(n, big.NewInt(1))
+	}
+
+	if negative {
+		n.Neg(n)
+	}
+
+	return formatScaled(n, scale)
+}
+
+// roundsAway decides whether the magnitude n, followed by the non-zero
+// fraction rem/den, should be incremented.
+func roundsAway(n, rem, den *big.Int, negative bool, mode RoundingMode) bool {
+	half := new(big.Int).Lsh(rem, 1).Cmp(den)
+
+	switch mode {
+	case Up:
+		return true
+	case Down:
+		return false
+	case Ceiling:
+		return !negative
+	case Floor:
+		return negative
+	case HalfUp:
+		return half >= 0
+	case HalfDown:
+		return half > 0
+	default:
+		return half > 0 || (half == 0 && n.Bit(0) == 1)
+	}
+}
+
+// formatScaled renders n * 10^-scale in plain notation.
+func formatScaled(n *big.Int, scale int) string {
+	digits := new(big.Int).Abs(n).String()
+	sign := ""
+	if n.Sign() < 0 {
+		sign = "-"
+	}
+
+	if scale <= 0 {
+		if n.Sign() == 0 {
+			return "0"
+		}
+
+		return sign + digits + strings.Repeat("0", -scale)
+	}
+
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+
+	intPart := digits[:len(digits)-scale]
+	fracPart := strings.TrimRight(digits[len(digits)-scale:], "0")
+	if fracPart == "" {
+		if n.Sign() == 0 {
+			return "0"
+		}
+
+		return sign + intPart
+	}
+
+	return sign + intPart + "." + fracPart
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func ratPow10(n int) *big.Rat {
+	if n < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), pow10(-n))
+	}
+
+	return new(big.Rat).SetInt(pow10(n))
+}
@@ -0,0 +1,125 @@
+package decimal
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFormatExact(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0", "0"},
+		{"-0", "0"},
+		{"3/10", "0.3"},
+		{"-1/8", "-0.125"},
+		{"123", "123"},
+		{"1200", "1200"},
+		{"1/1000000", "0.000001"},
+		{"25/2", "12.5"},
+		// No finite decimal expansion, rounded to DefaultPrecision digits
+		{"1/3", "0.3333333333333333333333333333333333"},
+		{"2/3", "0.6666666666666666666666666666666667"},
+		{"-1/6", "-0.1666666666666666666666666666666667"},
+		{"100/3", "33.33333333333333333333333333333333"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Format(rat(tt.input), 0, HalfEven); got != tt.expected {
+				t.Errorf("Format(%s, 0) = %s, expected %s", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatPrecision(t *testing.T) {
+	tests := []struct {
+		input     string
+		precision int
+		expected  string
+	}{
+		{"2/3", 10, "0.6666666667"},
+		{"12345", 2, "12000"},
+		{"12345", 10, "12345"},
+		{"999/1000", 2, "1"},
+		{"-9999", 1, "-10000"},
+		{"1/80", 1, "0.01"},
+		{"123/1000", 5, "0.123"},
+		{"0", 3, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Format(rat(tt.input), tt.precision, HalfEven); got != tt.expected {
+				t.Errorf("Format(%s, %d) = %s, expected %s", tt.input, tt.precision, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestRoundingModes rounds to one significant digit, the values and results
+// are the usual table of rounding modes.
+func TestRoundingModes(t *testing.T) {
+	inputs := []string{"11/2", "5/2", "8/5", "11/10", "1", "-1", "-11/10", "-8/5", "-5/2", "-11/2"}
+	tests := []struct {
+		mode     RoundingMode
+		expected []string
+	}{
+		{Up, []string{"6", "3", "2", "2", "1", "-1", "-2", "-2", "-3", "-6"}},
+		{Down, []string{"5", "2", "1", "1", "1", "-1", "-1", "-1", "-2", "-5"}},
+		{Ceiling, []string{"6", "3", "2", "2", "1", "-1", "-1", "-1", "-2", "-5"}},
+		{Floor, []string{"5", "2", "1", "1", "1", "-1", "-2", "-2", "-3", "-6"}},
+		{HalfUp, []string{"6", "3", "2", "1", "1", "-1", "-1", "-2", "-3", "-6"}},
+		{HalfDown, []string{"5", "2", "2", "1", "1", "-1", "-1", "-2", "-2", "-5"}},
+		{HalfEven, []string{"6", "2", "2", "1", "1", "-1", "-1", "-2", "-2", "-6"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			for i, input := range inputs {
+				if got := Format(rat(input), 1, tt.mode); got != tt.expected[i] {
+					t.Errorf("Format(%s, 1, %s) = %s, expected %s", input, tt.mode, got, tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseRoundingMode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected RoundingMode
+	}{
+		{"", HalfEven},
+		{"half_up", HalfUp},
+		{" CEILING ", Ceiling},
+		{"floor", Floor},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRoundingMode(tt.input)
+		if err != nil || got != tt.expected {
+			t.Errorf("ParseRoundingMode(%q) = %q, %v, expected %q", tt.input, got, err, tt.expected)
+		}
+	}
+
+	if _, err := ParseRoundingMode("nearest"); !errors.Is(err, ErrInvalidRounding) {
+		t.Errorf("ParseRoundingMode(nearest): got error %v, expected %v", err, ErrInvalidRounding)
+	}
+}
+
+func TestValidatePrecision(t *testing.T) {
+	for _, precision := range []int{0, 1, 34, MaxPrecision} {
+		if err := ValidatePrecision(precision); err != nil {
+			t.Errorf("ValidatePrecision(%d): unexpected error %v", precision, err)
+		}
+	}
+
+	for _, precision := range []int{-1, MaxPrecision + 1} {
+		if err := ValidatePrecision(precision); !errors.Is(err, ErrPrecisionRange) {
+			t.Errorf("ValidatePrecision(%d): got error %v, expected %v", precision, err, ErrPrecisionRange)
+		}
+	}
+}
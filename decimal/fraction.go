@@ -0,0 +1,58 @@
+package decimal
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrInvalidFraction = errors.New("invalid fraction")
+	ErrZeroDenominator = errors.New("the denominator of a fraction should not be zero")
+)
+
+var fractionPattern = regexp.MustCompile(`^[+-]?\d+/\d+$`)
+
+// ParseFraction converts a fraction such as "1/3" or "-22/7" into an exact
+// rational value. Numbers without a slash are parsed as decimals by Parse, so
+// that "0.5" and "1/2" are the same value.
+func ParseFraction(s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") {
+		return Parse(s)
+	}
+
+	if !fractionPattern.MatchString(s) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidFraction, s)
+	}
+
+	x, ok := new(big.Rat).SetString(s)
+	if !ok {
+		// SetString only fails on a zero denominator once the pattern matched
+		return nil, fmt.Errorf("%w: %q", ErrZeroDenominator, s)
+	}
+
+	return x, nil
+}
+
+// ParseFractions parses every string of xs, stopping at the first invalid one.
+func ParseFractions(xs []string) ([]*big.Rat, error) {
+	values := make([]*big.Rat, 0, len(xs))
+	for _, s := range xs {
+		x, err := ParseFraction(s)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, x)
+	}
+
+	return values, nil
+}
+
+// FormatFraction returns x as a reduced fraction such as "1/3", or as an
+// integer such as "-2" when its denominator is 1.
+func FormatFraction(x *big.Rat) string {
+	return x.RatString()
+}
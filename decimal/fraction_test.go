@@ -0,0 +1,84 @@
+package decimal
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseFraction(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1/3", "1/3"},
+		{"-22/7", "-22/7"},
+		{"2/4", "1/2"},
+		{"+6/3", "2"},
+		{" 4/8 ", "1/2"},
+		{"0/5", "0"},
+		// Numbers without a slash are decimals
+		{"0.5", "1/2"},
+		{"-3", "-3"},
+		{"1e3", "1000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseFraction(tt.input)
+			if err != nil {
+				t.Fatalf("ParseFraction(%q): unexpected error %v", tt.input, err)
+			}
+			if s := FormatFraction(got); s != tt.expected {
+				t.Errorf("ParseFraction(%q) = %s, expected %s", tt.input, s, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseFractionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected error
+	}{
+		{"1/0", ErrZeroDenominator},
+		{"-5/00", ErrZeroDenominator},
+		{"1/-3", ErrInvalidFraction},
+		{"a/b", ErrInvalidFraction},
+		{"1/2/3", ErrInvalidFraction},
+		{"0.5/2", ErrInvalidFraction},
+		{"/2", ErrInvalidFraction},
+		{"1/", ErrInvalidFraction},
+		{"abc", ErrInvalidNumber},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if _, err := ParseFraction(tt.input); !errors.Is(err, tt.expected) {
+				t.Errorf("ParseFraction(%q): got error %v, expected %v", tt.input, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFractionArithmetic(t *testing.T) {
+	values, err := ParseFractions([]string{"1/3", "1/6", "1/2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := FormatFraction(Sum(values)); got != "1" {
+		t.Errorf("1/3 + 1/6 + 1/2 = %s, expected 1", got)
+	}
+	if got := FormatFraction(Sub(values[0], values[2])); got != "-1/6" {
+		t.Errorf("1/3 - 1/2 = %s, expected -1/6", got)
+	}
+
+	quo, err := Quo(values[0], values[1])
+	if err != nil || FormatFraction(quo) != "2" {
+		t.Errorf("(1/3) / (1/6) = %v, %v, expected 2", quo, err)
+	}
+
+	if _, err := ParseFractions([]string{"1/3", "1/0"}); !errors.Is(err, ErrZeroDenominator) {
+		t.Errorf("ParseFractions: got error %v, expected %v", err, ErrZeroDenominator)
+	}
+}
@@ -6,10 +6,10 @@ import "github.com/swaggo/swag/v2"
 
 const docTemplate = `{
     "schemes": {{ marshal .Schemes }},
-    "components": {"schemas":{"main.APIError":{"properties":{"error":{"type":"string"}},"type":"object"},"main.APILoginSuccess":{"properties":{"token":{"type":"string"}},"type":"object"},"main.APISuccess":{"properties":{"result":{"type":"number"}},"type":"object"},"main.Payload":{"properties":{"number1":{"example":6,"type":"number"},"number2":{"example":9,"type":"number"}},"type":"object"},"main.PayloadLogin":{"properties":{"pseudo":{"example":"p4p1","type":"string"}},"type":"object"}},"securitySchemes":{"bearerauth":{"bearerFormat":"JWT","scheme":"bearer","type":"http"}}},
+    "components": {"schemas":{"complexmath.Number":{"properties":{"im":{"example":4,"type":"number"},"re":{"example":3,"type":"number"}},"type":"object"},"complexmath.Polar":{"properties":{"modulus":{"example":5,"type":"number"},"phase":{"example":0.9272952180016122,"type":"number"}},"type":"object"},"main.APIComplexSuccess":{"properties":{"result":{"$ref":"#/components/schemas/complexmath.Number"},"text":{"example":"3+4i","type":"string"}},"type":"object"},"main.APIDecimalSuccess":{"properties":{"result":{"example":"0.3","type":"string"}},"type":"object"},"main.APIError":{"properties":{"code":{"description":"Code tells why the inputs of a scientific function or of a complex\noperation, or the operands of a vector or matrix operation, are\ninvalid, and is not_finite for any result that overflows","example":"negative_root","type":"string"},"details":{},"error":{"type":"string"},"request_id":{"description":"RequestID is also returned in the X-Request-ID header","type":"string"}},"type":"object"},"main.APIFractionSuccess":{"properties":{"decimal":{"description":"Decimal is the result rounded as in the decimal mode","example":"0.3333333333333333333333333333333333","type":"string"},"result":{"description":"Result is the exact value, as a reduced fraction","example":"1/3","type":"string"}},"type":"object"},"main.APIKeyCreated":{"properties":{"created_at":{"type":"string"},"id":{"type":"integer"},"key":{"description":"Key is only returned once, at creation","type":"string"},"last_used_at":{"type":"string"},"name":{"type":"string"},"prefix":{"type":"string"},"revoked_at":{"type":"string"},"scopes":{"items":{"type":"string"},"type":"array","uniqueItems":false},"user_id":{"type":"integer"}},"type":"object"},"main.APILoginSuccess":{"properties":{"expires_in":{"description":"ExpiresIn is the lifetime of the access token in seconds","example":900,"type":"integer"},"refresh_token":{"type":"string"},"token":{"type":"string"}},"type":"object"},"main.APIMatrixSuccess":{"properties":{"result":{"items":{"items":{"type":"number"},"type":"array"},"type":"array","uniqueItems":false}},"type":"object"},"main.APIOperationsPage":{"properties":{"data":{"items":{"$ref":"#/components/schemas/repository.Operations"},"type":"array","uniqueItems":false},"next_cursor":{"description":"NextCursor is empty when there are no more operations to fetch","type":"string"}},"type":"object"},"main.APIPolarSuccess":{"properties":{"result":{"$ref":"#/components/schemas/complexmath.Polar"}},"type":"object"},"main.APIStatsSuccess":{"properties":{"result":{"$ref":"#/components/schemas/stats.Summary"}},"type":"object"},"main.APISuccess":{"properties":{"result":{"type":"number"}},"type":"object"},"main.APIVectorSuccess":{"properties":{"result":{"example":[0,0,1],"items":{"type":"number"},"type":"array","uniqueItems":false}},"type":"object"},"main.Payload":{"properties":{"number1":{"example":6,"type":"number"},"number2":{"example":9,"type":"number"}},"type":"object"},"main.PayloadAPIKey":{"properties":{"name":{"example":"nightly-batch","type":"string"},"scopes":{"example":["math:add","math:sum"],"items":{"type":"string"},"type":"array","uniqueItems":false}},"type":"object"},"main.PayloadAngle":{"properties":{"number":{"example":90,"type":"number"},"unit":{"$ref":"#/components/schemas/scientific.Unit"}},"type":"object"},"main.PayloadComplex":{"properties":{"number":{"$ref":"#/components/schemas/complexmath.Number"}},"type":"object"},"main.PayloadComplexAngle":{"properties":{"number":{"$ref":"#/components/schemas/complexmath.Number"},"unit":{"$ref":"#/components/schemas/scientific.Unit"}},"type":"object"},"main.PayloadComplexPair":{"properties":{"number1":{"$ref":"#/components/schemas/complexmath.Number"},"number2":{"$ref":"#/components/schemas/complexmath.Number"}},"type":"object"},"main.PayloadComplexPow":{"properties":{"base":{"$ref":"#/components/schemas/complexmath.Number"},"exponent":{"$ref":"#/components/schemas/complexmath.Number"}},"type":"object"},"main.PayloadComplexRoot":{"properties":{"degree":{"example":3,"type":"integer"},"number":{"$ref":"#/components/schemas/complexmath.Number"}},"type":"object"},"main.PayloadEvaluate":{"properties":{"expression":{"example":"(2 + 3) * 4 / 7","type":"string"}},"type":"object"},"main.PayloadLog":{"properties":{"base":{"example":2,"type":"number"},"number":{"example":8,"type":"number"}},"type":"object"},"main.PayloadLogin":{"properties":{"password":{"example":"correct-horse-42","type":"string"},"pseudo":{"example":"p4p1","type":"string"}},"type":"object"},"main.PayloadMatrices":{"properties":{"a":{"items":{"items":{"type":"number"},"type":"array"},"type":"array","uniqueItems":false},"b":{"items":{"items":{"type":"number"},"type":"array"},"type":"array","uniqueItems":false}},"type":"object"},"main.PayloadMatrix":{"properties":{"matrix":{"items":{"items":{"type":"number"},"type":"array"},"type":"array","uniqueItems":false}},"type":"object"},"main.PayloadNumber":{"properties":{"number":{"example":2,"type":"number"}},"type":"object"},"main.PayloadPolar":{"properties":{"modulus":{"example":5,"type":"number"},"phase":{"example":90,"type":"number"},"unit":{"$ref":"#/components/schemas/scientific.Unit"}},"type":"object"},"main.PayloadPow":{"properties":{"base":{"example":2,"type":"number"},"exponent":{"example":10,"type":"number"}},"type":"object"},"main.PayloadRefresh":{"properties":{"refresh_token":{"type":"string"}},"type":"object"},"main.PayloadRegister":{"properties":{"password":{"example":"correct-horse-42","type":"string"},"pseudo":{"example":"p4p1","type":"string"}},"type":"object"},"main.PayloadRole":{"properties":{"role":{"example":"read-only","type":"string","x-enum-varnames":["RoleAdmin","RoleUser","RoleReadOnly"]}},"type":"object"},"main.PayloadRoot":{"properties":{"degree":{"description":"Degree of the root, odd roots of negative numbers are real","example":3,"type":"integer"},"number":{"example":27,"type":"number"}},"type":"object"},"main.PayloadRound":{"properties":{"digits":{"description":"Digits after the decimal point, negative to round to tens, hundreds...","example":2,"type":"integer"},"number":{"example":3.14159,"type":"number"}},"type":"object"},"main.PayloadSolve":{"properties":{"a":{"items":{"items":{"type":"number"},"type":"array"},"type":"array","uniqueItems":false},"b":{"example":[8,-11,-3],"items":{"type":"number"},"type":"array","uniqueItems":false}},"type":"object"},"main.PayloadStats":{"properties":{"numbers":{"example":[1,2,2,3,4],"items":{"type":"number"},"type":"array","uniqueItems":false},"percentiles":{"description":"Percentiles to compute, between 0 and 100","example":[25,90],"items":{"type":"number"},"type":"array","uniqueItems":false}},"type":"object"},"main.PayloadVector":{"properties":{"vector":{"example":[3,4],"items":{"type":"number"},"type":"array","uniqueItems":false}},"type":"object"},"main.PayloadVectors":{"properties":{"a":{"example":[1,0,0],"items":{"type":"number"},"type":"array","uniqueItems":false},"b":{"example":[0,1,0],"items":{"type":"number"},"type":"array","uniqueItems":false}},"type":"object"},"repository.APIKey":{"properties":{"created_at":{"type":"string"},"id":{"type":"integer"},"last_used_at":{"type":"string"},"name":{"type":"string"},"prefix":{"type":"string"},"revoked_at":{"type":"string"},"scopes":{"items":{"type":"string"},"type":"array","uniqueItems":false},"user_id":{"type":"integer"}},"type":"object"},"repository.OperationMode":{"type":"string","x-enum-varnames":["ModeFloat","ModeDecimal","ModeFraction"]},"repository.OperationType":{"type":"string","x-enum-varnames":["TypeAdd","TypeSubstract","TypeMultiply","TypeDivide","TypeSum","TypeEvaluate","TypePow","TypeSqrt","TypeRoot","TypeExp","TypeLn","TypeLog10","TypeLog","TypeSin","TypeCos","TypeTan","TypeAsin","TypeAcos","TypeAtan","TypeAbs","TypeFloor","TypeCeil","TypeRound","TypeStats","TypeVectorDot","TypeVectorCross","TypeVectorNorm","TypeMatrixAdd","TypeMatrixMultiply","TypeMatrixTranspose","TypeMatrixDeterminant","TypeMatrixInverse","TypeMatrixSolve","TypeComplexAdd","TypeComplexSubstract","TypeComplexMultiply","TypeComplexDivide","TypeComplexMagnitude","TypeComplexPhase","TypeComplexConjugate","TypeComplexPolar","TypeComplexRectangular","TypeComplexPow","TypeComplexRoot"]},"repository.Operations":{"properties":{"created_at":{"type":"string"},"expression":{"type":"string"},"id":{"type":"integer"},"inputs":{"items":{"type":"string"},"type":"array","uniqueItems":false},"mode":{"$ref":"#/components/schemas/repository.OperationMode"},"request_id":{"type":"string"},"results":{"type":"string"},"type":{"$ref":"#/components/schemas/repository.OperationType"},"user_id":{"type":"integer"}},"type":"object"},"repository.Role":{"type":"string","x-enum-varnames":["RoleAdmin","RoleUser","RoleReadOnly"]},"repository.User":{"properties":{"id":{"type":"integer"},"pseudo":{"type":"string"},"role":{"$ref":"#/components/schemas/repository.Role"}},"type":"object"},"scientific.Unit":{"description":"Unit of the phase, defaults to radians","enum":["radians","degrees"],"example":"degrees","type":"string","x-enum-varnames":["Radians","Degrees"]},"stats.Percentile":{"properties":{"percentile":{"example":90,"type":"number"},"value":{"example":3.6,"type":"number"}},"type":"object"},"stats.Spread":{"properties":{"population":{"type":"number"},"sample":{"description":"Sample is null for a single number","type":"number"}},"type":"object"},"stats.Summary":{"properties":{"count":{"example":5,"type":"integer"},"max":{"example":4,"type":"number"},"mean":{"example":2.4,"type":"number"},"median":{"example":2,"type":"number"},"min":{"example":1,"type":"number"},"mode":{"description":"Mode lists the most frequent numbers, it is empty when they all appear\nonce","items":{"type":"number"},"type":"array","uniqueItems":false},"percentiles":{"description":"Percentiles are interpolated between the closest ranks, in the order\nthey were requested","items":{"$ref":"#/components/schemas/stats.Percentile"},"type":"array","uniqueItems":false},"std_dev":{"$ref":"#/components/schemas/stats.Spread"},"sum":{"description":"Sum is null when it is too large for a float64, like the spreads; the\nother statistics lie between the smallest and largest numbers","example":12,"type":"number"},"variance":{"$ref":"#/components/schemas/stats.Spread"}},"type":"object"}},"securitySchemes":{"ApiKeyAuth":{"in":"header","name":"X-API-Key","type":"apiKey"},"BearerAuth":{"bearerFormat":"JWT","scheme":"bearer","type":"http"}}},
     "info": {"contact":{"email":"pa.ndoye@outlook.com","name":"Abdoulaye NDOYE","url":"https://github.com/NDOY3M4N"},"description":"{{escape .Description}}","license":{"name":"MIT","url":"https://github.com/NDOY3M4N/api-calculator/blob/main/LICENSE.md"},"title":"{{.Title}}","version":"{{.Version}}"},
     "externalDocs": {"description":"","url":""},
-    "paths": {"/add":{"post":{"description":"Add two numbers together","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.Payload"}}},"description":"Numbers needed for the operation","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]}],"summary":"Add two numbers","tags":["Math"]}},"/divide":{"post":{"description":"Divide two numbers together","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.Payload"}}},"description":"Numbers needed for the operation","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]}],"summary":"Divide two numbers","tags":["Math"]}},"/login":{"post":{"description":"Log the user","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadLogin"}}},"description":"Field needed for login","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APILoginSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"summary":"Login","tags":["User"]}},"/multiply":{"post":{"description":"Multiply two numbers together","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.Payload"}}},"description":"Numbers needed for the operation","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]}],"summary":"Multiply two numbers","tags":["Math"]}},"/substract":{"post":{"description":"Substract two numbers together","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.Payload"}}},"description":"Numbers needed for the operation","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]}],"summary":"Substract two numbers","tags":["Math"]}},"/sum":{"post":{"description":"Add all numbers in an array","requestBody":{"content":{"application/json":{"schema":{"items":{"type":"number"},"type":"array"}}},"description":"Array of numbers needed for the operation","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]}],"summary":"Sum numbers","tags":["Math"]}}},
+    "paths": {"/abs":{"post":{"description":"Compute the square root, exponential, natural or decimal logarithm, absolute value, floor or ceiling of a number. The square root of a negative number and the logarithm of a non-positive one are rejected.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadNumber"}}},"description":"Number to apply the function to","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Apply a function to a number","tags":["Scientific"]}},"/acos":{"post":{"description":"Compute the sine, cosine or tangent of an angle, or the arc sine, arc cosine or arc tangent of a number as an angle, in radians or degrees. The arc sine and arc cosine are only defined between -1 and 1, the tangent of an odd multiple of 90 degrees is rejected.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadAngle"}}},"description":"Number and unit of the angle","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Apply a trigonometric function","tags":["Scientific"]}},"/add":{"post":{"description":"Add two numbers together","parameters":[{"description":"Set to \\","in":"header","name":"X-Calc-Mode","schema":{"enum":["float","decimal","fraction"],"type":"string"}},{"description":"Significant digits of a decimal result, 0 for exact","in":"header","name":"X-Calc-Precision","schema":{"type":"integer"}},{"description":"Rounding mode of a decimal result","in":"header","name":"X-Calc-Rounding","schema":{"enum":["half_even","half_up","half_down","up","down","ceiling","floor"],"type":"string"}}],"requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.Payload"}}},"description":"Numbers needed for the operation","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"oneOf":[{"$ref":"#/components/schemas/main.APISuccess"},{"$ref":"#/components/schemas/main.APIDecimalSuccess"},{"$ref":"#/components/schemas/main.APIFractionSuccess"}]}}},"description":"Result in fraction mode"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Add two numbers","tags":["Math"]}},"/api-keys":{"get":{"description":"List the API keys of the authenticated user, revoked ones included","responses":{"200":{"content":{"application/json":{"schema":{"items":{"$ref":"#/components/schemas/repository.APIKey"},"type":"array"}}},"description":"OK"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"List API keys","tags":["API keys"]},"post":{"description":"Create a long-lived key to send in the X-API-Key header. The\nkey is only shown in this response.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadAPIKey"}}},"description":"Name and scopes of the key","required":true},"responses":{"201":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIKeyCreated"}}},"description":"Created"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Create an API key","tags":["API keys"]}},"/api-keys/{id}":{"delete":{"description":"Revoke an API key of the authenticated user","parameters":[{"description":"API key ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"responses":{"204":{"description":"No Content"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Not Found"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Revoke an API key","tags":["API keys"]}},"/asin":{"post":{"description":"Compute the sine, cosine or tangent of an angle, or the arc sine, arc cosine or arc tangent of a number as an angle, in radians or degrees. The arc sine and arc cosine are only defined between -1 and 1, the tangent of an odd multiple of 90 degrees is rejected.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadAngle"}}},"description":"Number and unit of the angle","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Apply a trigonometric function","tags":["Scientific"]}},"/atan":{"post":{"description":"Compute the sine, cosine or tangent of an angle, or the arc sine, arc cosine or arc tangent of a number as an angle, in radians or degrees. The arc sine and arc cosine are only defined between -1 and 1, the tangent of an odd multiple of 90 degrees is rejected.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadAngle"}}},"description":"Number and unit of the angle","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Apply a trigonometric function","tags":["Scientific"]}},"/ceil":{"post":{"description":"Compute the square root, exponential, natural or decimal logarithm, absolute value, floor or ceiling of a number. The square root of a negative number and the logarithm of a non-positive one are rejected.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadNumber"}}},"description":"Number to apply the function to","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Apply a function to a number","tags":["Scientific"]}},"/complex/add":{"post":{"description":"Add, substract, multiply or divide two complex numbers","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadComplexPair"}}},"description":"Complex numbers, as {re, im} objects or a+bi strings","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIComplexSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Complex arithmetic","tags":["Complex numbers"]}},"/complex/conjugate":{"post":{"description":"Compute the conjugate of a complex number, a-bi for a+bi","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadComplex"}}},"description":"Complex number, as a {re, im} object or an a+bi string","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIComplexSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Conjugate of a complex number","tags":["Complex numbers"]}},"/complex/divide":{"post":{"description":"Add, substract, multiply or divide two complex numbers","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadComplexPair"}}},"description":"Complex numbers, as {re, im} objects or a+bi strings","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIComplexSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Complex arithmetic","tags":["Complex numbers"]}},"/complex/magnitude":{"post":{"description":"Compute the modulus of a complex number","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadComplex"}}},"description":"Complex number, as a {re, im} object or an a+bi string","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Magnitude of a complex number","tags":["Complex numbers"]}},"/complex/multiply":{"post":{"description":"Add, substract, multiply or divide two complex numbers","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadComplexPair"}}},"description":"Complex numbers, as {re, im} objects or a+bi strings","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIComplexSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Complex arithmetic","tags":["Complex numbers"]}},"/complex/phase":{"post":{"description":"Compute the phase of a complex number, between -π and π radians or -180 and 180 degrees","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadComplexAngle"}}},"description":"Complex number and unit of the phase","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Phase of a complex number","tags":["Complex numbers"]}},"/complex/polar":{"post":{"description":"Convert a complex number to its modulus and phase","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadComplexAngle"}}},"description":"Complex number and unit of the phase","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIPolarSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Polar form of a complex number","tags":["Complex numbers"]}},"/complex/pow":{"post":{"description":"Raise a complex number to a complex power, on the principal branch. Integer powers are exact, e.g. i² is -1.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadComplexPow"}}},"description":"Base and exponent, as {re, im} objects or a+bi strings","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIComplexSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Complex power","tags":["Complex numbers"]}},"/complex/rectangular":{"post":{"description":"Convert a modulus and a phase to a complex number. In degrees, the multiples of 90 are exact.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadPolar"}}},"description":"Modulus, phase and unit of the phase","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIComplexSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Rectangular form of a complex number","tags":["Complex numbers"]}},"/complex/root":{"post":{"description":"Compute the principal root of any degree of a complex number, e.g. the square root of -4 is 2i","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadComplexRoot"}}},"description":"Complex number and degree of the root","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIComplexSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Complex root","tags":["Complex numbers"]}},"/complex/substract":{"post":{"description":"Add, substract, multiply or divide two complex numbers","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadComplexPair"}}},"description":"Complex numbers, as {re, im} objects or a+bi strings","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIComplexSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Complex arithmetic","tags":["Complex numbers"]}},"/cos":{"post":{"description":"Compute the sine, cosine or tangent of an angle, or the arc sine, arc cosine or arc tangent of a number as an angle, in radians or degrees. The arc sine and arc cosine are only defined between -1 and 1, the tangent of an odd multiple of 90 degrees is rejected.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadAngle"}}},"description":"Number and unit of the angle","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Apply a trigonometric function","tags":["Scientific"]}},"/divide":{"post":{"description":"Divide two numbers together","parameters":[{"description":"Set to \\","in":"header","name":"X-Calc-Mode","schema":{"enum":["float","decimal","fraction"],"type":"string"}},{"description":"Significant digits of a decimal result, 0 for exact","in":"header","name":"X-Calc-Precision","schema":{"type":"integer"}},{"description":"Rounding mode of a decimal result","in":"header","name":"X-Calc-Rounding","schema":{"enum":["half_even","half_up","half_down","up","down","ceiling","floor"],"type":"string"}}],"requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.Payload"}}},"description":"Numbers needed for the operation","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"oneOf":[{"$ref":"#/components/schemas/main.APISuccess"},{"$ref":"#/components/schemas/main.APIDecimalSuccess"},{"$ref":"#/components/schemas/main.APIFractionSuccess"}]}}},"description":"Result in fraction mode"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Divide two numbers","tags":["Math"]}},"/evaluate":{"post":{"description":"Evaluate an infix expression using +, -, *, /, ^ and parentheses","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadEvaluate"}}},"description":"Expression to evaluate","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Evaluate an expression","tags":["Math"]}},"/exp":{"post":{"description":"Compute the square root, exponential, natural or decimal logarithm, absolute value, floor or ceiling of a number. The square root of a negative number and the logarithm of a non-positive one are rejected.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadNumber"}}},"description":"Number to apply the function to","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Apply a function to a number","tags":["Scientific"]}},"/floor":{"post":{"description":"Compute the square root, exponential, natural or decimal logarithm, absolute value, floor or ceiling of a number. The square root of a negative number and the logarithm of a non-positive one are rejected.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadNumber"}}},"description":"Number to apply the function to","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Apply a function to a number","tags":["Scientific"]}},"/ln":{"post":{"description":"Compute the square root, exponential, natural or decimal logarithm, absolute value, floor or ceiling of a number. The square root of a negative number and the logarithm of a non-positive one are rejected.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadNumber"}}},"description":"Number to apply the function to","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Apply a function to a number","tags":["Scientific"]}},"/log":{"post":{"description":"Compute the logarithm of a positive number in a positive base other than 1","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadLog"}}},"description":"Number and base of the logarithm","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Compute a logarithm","tags":["Scientific"]}},"/log10":{"post":{"description":"Compute the square root, exponential, natural or decimal logarithm, absolute value, floor or ceiling of a number. The square root of a negative number and the logarithm of a non-positive one are rejected.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadNumber"}}},"description":"Number to apply the function to","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Apply a function to a number","tags":["Scientific"]}},"/login":{"post":{"description":"Log the user in with their pseudo and password. The account is\nlocked for a while after too many failed attempts.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadLogin"}}},"description":"Field needed for login","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APILoginSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"},"401":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Unauthorized"},"423":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Locked"}},"summary":"Login","tags":["User"]}},"/logout":{"post":{"description":"Revoke the access token used for this request and, when it is\ngiven, the refresh token obtained with it","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadRefresh"}}},"description":"Refresh token to revoke as well"},"responses":{"204":{"description":"No Content"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]}],"summary":"Logout","tags":["User"]}},"/matrices/add":{"post":{"description":"Add two matrices of the same dimensions, or multiply a by b, b having as many rows as a has columns","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadMatrices"}}},"description":"Matrices a and b, as arrays of rows","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIMatrixSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Add or multiply two matrices","tags":["Linear algebra"]}},"/matrices/determinant":{"post":{"description":"Compute the determinant of a square matrix, 0 for a singular one","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadMatrix"}}},"description":"Matrix, as an array of rows","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Determinant of a matrix","tags":["Linear algebra"]}},"/matrices/inverse":{"post":{"description":"Transpose a matrix, or invert a square matrix. Singular matrices cannot be inverted.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadMatrix"}}},"description":"Matrix, as an array of rows","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIMatrixSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Transpose or invert a matrix","tags":["Linear algebra"]}},"/matrices/multiply":{"post":{"description":"Add two matrices of the same dimensions, or multiply a by b, b having as many rows as a has columns","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadMatrices"}}},"description":"Matrices a and b, as arrays of rows","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIMatrixSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Add or multiply two matrices","tags":["Linear algebra"]}},"/matrices/solve":{"post":{"description":"Solve Ax = b for a square non-singular matrix A","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadSolve"}}},"description":"Matrix a, as an array of rows, and vector b","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIVectorSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Solve a linear system","tags":["Linear algebra"]}},"/matrices/transpose":{"post":{"description":"Transpose a matrix, or invert a square matrix. Singular matrices cannot be inverted.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadMatrix"}}},"description":"Matrix, as an array of rows","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIMatrixSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Transpose or invert a matrix","tags":["Linear algebra"]}},"/multiply":{"post":{"description":"Multiply two numbers together","parameters":[{"description":"Set to \\","in":"header","name":"X-Calc-Mode","schema":{"enum":["float","decimal","fraction"],"type":"string"}},{"description":"Significant digits of a decimal result, 0 for exact","in":"header","name":"X-Calc-Precision","schema":{"type":"integer"}},{"description":"Rounding mode of a decimal result","in":"header","name":"X-Calc-Rounding","schema":{"enum":["half_even","half_up","half_down","up","down","ceiling","floor"],"type":"string"}}],"requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.Payload"}}},"description":"Numbers needed for the operation","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"oneOf":[{"$ref":"#/components/schemas/main.APISuccess"},{"$ref":"#/components/schemas/main.APIDecimalSuccess"},{"$ref":"#/components/schemas/main.APIFractionSuccess"}]}}},"description":"Result in fraction mode"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Multiply two numbers","tags":["Math"]}},"/operations":{"get":{"description":"List the calculation history of the authenticated user","parameters":[{"description":"Operation type","in":"query","name":"type","schema":{"enum":["add","substract","multiply","divide","sum","evaluate","pow","sqrt","root","exp","ln","log10","log","sin","cos","tan","asin","acos","atan","abs","floor","ceil","round","stats","vector_dot","vector_cross","vector_norm","matrix_add","matrix_multiply","matrix_transpose","matrix_determinant","matrix_inverse","matrix_solve","complex_add","complex_substract","complex_multiply","complex_divide","complex_magnitude","complex_phase","complex_conjugate","complex_polar","complex_rectangular","complex_pow","complex_root"],"type":"string"}},{"description":"Only the operation recorded by this request","in":"query","name":"request_id","schema":{"type":"string"}},{"description":"Only operations created at or after this RFC 3339 date","in":"query","name":"from","schema":{"type":"string"}},{"description":"Only operations created at or before this RFC 3339 date","in":"query","name":"to","schema":{"type":"string"}},{"description":"Minimum result, statistics, vectors, matrices and complex numbers are skipped","in":"query","name":"min_result","schema":{"type":"number"}},{"description":"Maximum result, statistics, vectors, matrices and complex numbers are skipped","in":"query","name":"max_result","schema":{"type":"number"}},{"description":"Sort order by creation","in":"query","name":"order","schema":{"enum":["desc","asc"],"type":"string"}},{"description":"Page size (1-100)","in":"query","name":"limit","schema":{"type":"integer"}},{"description":"Cursor returned by the previous page","in":"query","name":"cursor","schema":{"type":"string"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIOperationsPage"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"List operations","tags":["History"]}},"/operations/{id}":{"get":{"description":"Get one operation of the authenticated user","parameters":[{"description":"Operation ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/repository.Operations"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Not Found"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Get an operation","tags":["History"]}},"/pow":{"post":{"description":"Raise a base to an exponent. Zero cannot be raised to a negative power, nor a negative base to a fractional one.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadPow"}}},"description":"Base and exponent","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Raise to a power","tags":["Scientific"]}},"/register":{"post":{"description":"Create a user with a password","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadRegister"}}},"description":"Fields needed for registration","required":true},"responses":{"201":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/repository.User"}}},"description":"Created"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"},"409":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Conflict"}},"summary":"Register","tags":["User"]}},"/root":{"post":{"description":"Compute the root of any non-zero degree of a number. Even roots of negative numbers are rejected.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadRoot"}}},"description":"Number and degree of the root","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Compute the root of a number","tags":["Scientific"]}},"/round":{"post":{"description":"Round a number half away from zero to a number of decimals, between -15 and 15","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadRound"}}},"description":"Number and decimals to keep","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Round a number","tags":["Scientific"]}},"/sin":{"post":{"description":"Compute the sine, cosine or tangent of an angle, or the arc sine, arc cosine or arc tangent of a number as an angle, in radians or degrees. The arc sine and arc cosine are only defined between -1 and 1, the tangent of an odd multiple of 90 degrees is rejected.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadAngle"}}},"description":"Number and unit of the angle","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Apply a trigonometric function","tags":["Scientific"]}},"/sqrt":{"post":{"description":"Compute the square root, exponential, natural or decimal logarithm, absolute value, floor or ceiling of a number. The square root of a negative number and the logarithm of a non-positive one are rejected.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadNumber"}}},"description":"Number to apply the function to","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Apply a function to a number","tags":["Scientific"]}},"/stats":{"post":{"description":"Compute the count, sum, minimum, maximum, mean, median, mode, variance and standard deviation of an array of numbers, and the requested percentiles. The sum is compensated and the variance computed with Welford's algorithm.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadStats"}}},"description":"Numbers to describe and percentiles to compute","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIStatsSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Describe numbers","tags":["Math"]}},"/substract":{"post":{"description":"Substract two numbers together","parameters":[{"description":"Set to \\","in":"header","name":"X-Calc-Mode","schema":{"enum":["float","decimal","fraction"],"type":"string"}},{"description":"Significant digits of a decimal result, 0 for exact","in":"header","name":"X-Calc-Precision","schema":{"type":"integer"}},{"description":"Rounding mode of a decimal result","in":"header","name":"X-Calc-Rounding","schema":{"enum":["half_even","half_up","half_down","up","down","ceiling","floor"],"type":"string"}}],"requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.Payload"}}},"description":"Numbers needed for the operation","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"oneOf":[{"$ref":"#/components/schemas/main.APISuccess"},{"$ref":"#/components/schemas/main.APIDecimalSuccess"},{"$ref":"#/components/schemas/main.APIFractionSuccess"}]}}},"description":"Result in fraction mode"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Substract two numbers","tags":["Math"]}},"/sum":{"post":{"description":"Add all numbers in an array","parameters":[{"description":"Set to \\","in":"header","name":"X-Calc-Mode","schema":{"enum":["float","decimal","fraction"],"type":"string"}},{"description":"Significant digits of a decimal result, 0 for exact","in":"header","name":"X-Calc-Precision","schema":{"type":"integer"}},{"description":"Rounding mode of a decimal result","in":"header","name":"X-Calc-Rounding","schema":{"enum":["half_even","half_up","half_down","up","down","ceiling","floor"],"type":"string"}}],"requestBody":{"content":{"application/json":{"schema":{"items":{"type":"number"},"type":"array"}}},"description":"Array of numbers needed for the operation","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"oneOf":[{"$ref":"#/components/schemas/main.APISuccess"},{"$ref":"#/components/schemas/main.APIDecimalSuccess"},{"$ref":"#/components/schemas/main.APIFractionSuccess"}]}}},"description":"Result in fraction mode"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Sum numbers","tags":["Math"]}},"/tan":{"post":{"description":"Compute the sine, cosine or tangent of an angle, or the arc sine, arc cosine or arc tangent of a number as an angle, in radians or degrees. The arc sine and arc cosine are only defined between -1 and 1, the tangent of an odd multiple of 90 degrees is rejected.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadAngle"}}},"description":"Number and unit of the angle","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Apply a trigonometric function","tags":["Scientific"]}},"/token/refresh":{"post":{"description":"Exchange a refresh token for a new access token and a new\nrefresh token. A refresh token can only be used once: using\nit again revokes every token obtained from the same login.","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadRefresh"}}},"description":"Refresh token received at login","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APILoginSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"},"401":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Unauthorized"}},"summary":"Refresh the access token","tags":["User"]}},"/users":{"get":{"description":"List every user along with their role","responses":{"200":{"content":{"application/json":{"schema":{"items":{"$ref":"#/components/schemas/repository.User"},"type":"array"}}},"description":"OK"},"403":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Forbidden"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"List users","tags":["Admin"]}},"/users/{id}/role":{"put":{"description":"Change the role of a user. Their sessions are revoked so that\ntokens carrying the previous role stop working.","parameters":[{"description":"User ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadRole"}}},"description":"New role","required":true},"responses":{"204":{"description":"No Content"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"},"403":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Forbidden"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Not Found"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Change the role of a user","tags":["Admin"]}},"/users/{id}/sessions":{"delete":{"description":"Revoke every access and refresh token of a user, e.g. after a\ndevice was lost. Revoking the sessions of another user requires\nthe users:admin scope.","parameters":[{"description":"User ID","in":"path","name":"id","required":true,"schema":{"type":"integer"}}],"responses":{"204":{"description":"No Content"},"403":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Forbidden"},"404":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Not Found"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Revoke all sessions of a user","tags":["User"]}},"/vectors/cross":{"post":{"description":"Compute the cross product of two 3-dimensional vectors","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadVectors"}}},"description":"Vectors a and b","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIVectorSuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Cross product","tags":["Linear algebra"]}},"/vectors/dot":{"post":{"description":"Compute the dot product of two vectors of the same length","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadVectors"}}},"description":"Vectors a and b","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Dot product","tags":["Linear algebra"]}},"/vectors/norm":{"post":{"description":"Compute the Euclidean norm of a vector","requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.PayloadVector"}}},"description":"Vector","required":true},"responses":{"200":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APISuccess"}}},"description":"OK"},"400":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/main.APIError"}}},"description":"Bad Request"}},"security":[{"BearerAuth":[]},{"ApiKeyAuth":[]}],"summary":"Norm of a vector","tags":["Linear algebra"]}}},
     "openapi": "3.1.0",
     "servers": [
         {"description":"Development server","url":"http://localhost:3000/api/v1"}
@@ -0,0 +1,159 @@
+// Package expression parses and evaluates infix arithmetic expressions such as
+// "(2 + 3) * 4 / 7".
+//
+// The grammar supports the binary operators + - * / and ^, parentheses and
+// unary plus/minus. Operators follow the usual precedence: ^ binds tighter than
+// unary minus (so -2^2 is -4) and is right-associative, * and / bind tighter
+// than + and -, and all of them are left-associative.
+package expression
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrEmpty          = errors.New("expression should not be empty")
+	ErrDivisionByZero = errors.New("division by zero is prohibited")
+	ErrNotFinite      = errors.New("result is not a finite number")
+)
+
+// ParseError describes where and why an expression could not be parsed.
+type ParseError struct {
+	// Position is the 1-based column where the error was detected
+	Position int    `json:"position"`
+	Expected string `json:"expected"`
+	Found    string `json:"found"`
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("unexpected %s at position %d, expected %s", e.Found, e.Position, e.Expected)
+}
+
+// node is an element of a parsed expression tree.
+type node interface {
+	eval() (float64, error)
+}
+
+type numberNode struct {
+	value float64
+}
+
+type unaryNode struct {
+	op      tokenKind
+	operand node
+}
+
+type binaryNode struct {
+	op          tokenKind
+	left, right node
+}
+
+// Expression is a parsed expression ready to be evaluated.
+type Expression struct {
+	root    node
+	numbers []float64
+}
+
+// Parse parses input into an Expression. A syntax error is reported as a
+// *ParseError.
+func Parse(input string) (*Expression, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 1 {
+		return nil, ErrEmpty
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorf(tok, "operator or end of expression")
+	}
+
+	return &Expression{root: root, numbers: p.numbers}, nil
+}
+
+// Numbers returns the number literals of the expression in the order they
+// appear.
+func (e *Expression) Numbers() []float64 {
+	return e.numbers
+}
+
+// Eval computes the value of the expression.
+func (e *Expression) Eval() (float64, error) {
+	result, err := e.root.eval()
+	if err != nil {
+		return 0, err
+	}
+
+	if math.IsInf(result, 0) || math.IsNaN(result) {
+		return 0, ErrNotFinite
+	}
+
+	return result, nil
+}
+
+// Evaluate parses and evaluates input in one step.
+func Evaluate(input string) (float64, error) {
+	expr, err := Parse(input)
+	if err != nil {
+		return 0, err
+	}
+
+	return expr.Eval()
+}
+
+func (n *numberNode) eval() (float64, error) {
+	return n.value, nil
+}
+
+func (n *unaryNode) eval() (float64, error) {
+	value, err := n.operand.eval()
+	if err != nil {
+		return 0, err
+	}
+
+	if n.op == tokenMinus {
+		return -value, nil
+	}
+
+	return value, nil
+}
+
+func (n *binaryNode) eval() (float64, error) {
+	left, err := n.left.eval()
+	if err != nil {
+		return 0, err
+	}
+
+	right, err := n.right.eval()
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case tokenPlus:
+		return left + right, nil
+	case tokenMinus:
+		return left - right, nil
+	case tokenStar:
+		return left * right, nil
+	case tokenSlash:
+		if right == 0 {
+			return 0, ErrDivisionByZero
+		}
+		return left / right, nil
+	case tokenCaret:
+		return math.Pow(left, right), nil
+	default:
+		return 0, fmt.Errorf("unsupported operator %s", n.op)
+	}
+}
@@ -0,0 +1,121 @@
+package expression
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"42", 42},
+		{"1.5", 1.5},
+		{".5", 0.5},
+		{"1e-3", 0.001},
+		{"2.5E+2", 250},
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"(2 + 3) * 4 / 5", 4},
+		{"10 - 4 - 3", 3},
+		{"24 / 4 / 2", 3},
+		{"2 * 3 + 4 * 5", 26},
+		{"2 ^ 3 ^ 2", 512},
+		{"2 ^ -1", 0.5},
+		{"-2 ^ 2", -4},
+		{"(-2) ^ 2", 4},
+		{"-3", -3},
+		{"--3", 3},
+		{"+3", 3},
+		{"-(1 + 2)", -3},
+		{"2 * -3", -6},
+		{"1 - -1", 2},
+		{"((((1))))", 1},
+		{" \t1+\n2\r", 3},
+		{strings.Repeat("-", MaxDepth-1) + "1", -1},
+		{strings.Repeat("(", MaxDepth-1) + "1" + strings.Repeat(")", MaxDepth-1), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input[:min(len(tt.input), 20)], func(t *testing.T) {
+			got, err := Evaluate(tt.input)
+			if err != nil {
+				t.Fatalf("Evaluate(%q): unexpected error %v", tt.input, err)
+			}
+			if got != tt.expected {
+				t.Errorf("Evaluate(%q) = %v, expected %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected error
+	}{
+		{"", ErrEmpty},
+		{"   ", ErrEmpty},
+		{"1 / 0", ErrDivisionByZero},
+		{"1 / (2 - 2)", ErrDivisionByZero},
+		{"0 / 0", ErrDivisionByZero},
+		{"10 ^ 400", ErrNotFinite},
+		{"(-8) ^ 0.5", ErrNotFinite},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if _, err := Evaluate(tt.input); !errors.Is(err, tt.expected) {
+				t.Errorf("Evaluate(%q): got error %v, expected %v", tt.input, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ParseError
+	}{
+		{"1 +", ParseError{Position: 4, Expected: "number or '('", Found: "end of expression"}},
+		{"(1 + 2", ParseError{Position: 7, Expected: "')'", Found: "end of expression"}},
+		{"1 + 2)", ParseError{Position: 6, Expected: "operator or end of expression", Found: "')'"}},
+		{"1 2", ParseError{Position: 3, Expected: "operator or end of expression", Found: "number 2"}},
+		{"* 2", ParseError{Position: 1, Expected: "number or '('", Found: "'*'"}},
+		{"()", ParseError{Position: 2, Expected: "number or '('", Found: "')'"}},
+		{"2 % 3", ParseError{Position: 3, Expected: "number, operator or parenthesis", Found: "'%'"}},
+		{"1..2", ParseError{Position: 1, Expected: "finite number", Found: `"1..2"`}},
+		{"1e999", ParseError{Position: 1, Expected: "finite number", Found: `"1e999"`}},
+		{strings.Repeat("-", MaxDepth) + "1", ParseError{Position: MaxDepth + 1, Expected: "at most 200 levels of nesting", Found: "number 1"}},
+		{strings.Repeat("(", MaxDepth+1) + "1", ParseError{Position: MaxDepth + 1, Expected: "at most 200 levels of nesting", Found: "'('"}},
+		{"2" + strings.Repeat("^2", MaxDepth), ParseError{Position: 2*MaxDepth + 1, Expected: "at most 200 levels of nesting", Found: "number 2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input[:min(len(tt.input), 20)], func(t *testing.T) {
+			_, err := Parse(tt.input)
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Parse(%q): got error %v, expected a *ParseError", tt.input, err)
+			}
+			if *parseErr != tt.expected {
+				t.Errorf("Parse(%q): got %+v, expected %+v", tt.input, *parseErr, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	expr, err := Parse("(1.5 + 2) * -3 ^ 4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, expected := expr.Numbers(), []float64{1.5, 2, 3, 4}; !slices.Equal(got, expected) {
+		t.Errorf("Numbers() = %v, expected %v", got, expected)
+	}
+}
@@ -0,0 +1,151 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenPlus
+	tokenMinus
+	tokenStar
+	tokenSlash
+	tokenCaret
+	tokenLParen
+	tokenRParen
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenEOF:
+		return "end of expression"
+	case tokenNumber:
+		return "number"
+	case tokenPlus:
+		return "'+'"
+	case tokenMinus:
+		return "'-'"
+	case tokenStar:
+		return "'*'"
+	case tokenSlash:
+		return "'/'"
+	case tokenCaret:
+		return "'^'"
+	case tokenLParen:
+		return "'('"
+	case tokenRParen:
+		return "')'"
+	default:
+		return "unknown token"
+	}
+}
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value float64
+	// pos is the 1-based column of the first character of the token
+	pos int
+}
+
+func (t token) describe() string {
+	if t.kind == tokenNumber {
+		return fmt.Sprintf("number %s", t.text)
+	}
+
+	return t.kind.String()
+}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(input); {
+		c := input[i]
+		pos := i + 1
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case isDigit(c) || c == '.':
+			start := i
+			i = scanNumber(input, i)
+			text := input[start:i]
+
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, &ParseError{
+					Position: pos,
+					Expected: "finite number",
+					Found:    fmt.Sprintf("%q", text),
+				}
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: text, value: value, pos: pos})
+			continue
+		}
+
+		var kind tokenKind
+		switch c {
+		case '+':
+			kind = tokenPlus
+		case '-':
+			kind = tokenMinus
+		case '*':
+			kind = tokenStar
+		case '/':
+			kind = tokenSlash
+		case '^':
+			kind = tokenCaret
+		case '(':
+			kind = tokenLParen
+		case ')':
+			kind = tokenRParen
+		default:
+			return nil, &ParseError{
+				Position: pos,
+				Expected: "number, operator or parenthesis",
+				Found:    fmt.Sprintf("%q", c),
+			}
+		}
+
+		tokens = append(tokens, token{kind: kind, text: string(c), pos: pos})
+		i++
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(input) + 1})
+
+	return tokens, nil
+}
+
+// scanNumber returns the index right after the number literal starting at i.
+// It accepts an optional fractional part and exponent, e.g. "1", "1.5", ".5"
+// and "1e-3".
+func scanNumber(input string, i int) int {
+	for i < len(input) && (isDigit(input[i]) || input[i] == '.') {
+		i++
+	}
+
+	if i < len(input) && (input[i] == 'e' || input[i] == 'E') {
+		j := i + 1
+		if j < len(input) && (input[j] == '+' || input[j] == '-') {
+			j++
+		}
+		if j < len(input) && isDigit(input[j]) {
+			for j < len(input) && isDigit(input[j]) {
+				j++
+			}
+			i = j
+		}
+	}
+
+	return i
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
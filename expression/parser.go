@@ -0,0 +1,153 @@
+package expression
+
+import "fmt"
+
+// MaxDepth bounds the nesting of the unary operators, exponents and
+// parentheses, a number alone being one level. The parser recurses at each
+// level, and an overflow of the stack would stop the whole process.
+const MaxDepth = 200
+
+// Grammar, from lowest to highest precedence:
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/") unary }
+//	unary   = ("+" | "-") unary | power
+//	power   = primary [ "^" unary ]
+//	primary = number | "(" expr ")"
+type parser struct {
+	tokens  []token
+	pos     int
+	numbers []float64
+	// depth is the number of parseUnary calls in progress, every recursion
+	// of the grammar goes through it
+	depth int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+
+	return tok
+}
+
+func (p *parser) errorf(tok token, expected string) *ParseError {
+	return &ParseError{
+		Position: tok.pos,
+		Expected: expected,
+		Found:    tok.describe(),
+	}
+}
+
+func (p *parser) parseExpr() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op := p.peek().kind
+		if op != tokenPlus && op != tokenMinus {
+			return left, nil
+		}
+		p.next()
+
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseTerm() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op := p.peek().kind
+		if op != tokenStar && op != tokenSlash {
+			return left, nil
+		}
+		p.next()
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.depth++; p.depth > MaxDepth {
+		return nil, p.errorf(p.peek(), fmt.Sprintf("at most %d levels of nesting", MaxDepth))
+	}
+	defer func() { p.depth-- }()
+
+	if op := p.peek().kind; op == tokenPlus || op == tokenMinus {
+		p.next()
+
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &unaryNode{op: op, operand: operand}, nil
+	}
+
+	return p.parsePower()
+}
+
+func (p *parser) parsePower() (node, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind != tokenCaret {
+		return base, nil
+	}
+	p.next()
+
+	// Recursing through parseUnary makes ^ right-associative and allows a
+	// signed exponent such as 2^-1
+	exponent, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &binaryNode{op: tokenCaret, left: base, right: exponent}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenNumber:
+		p.numbers = append(p.numbers, tok.value)
+		return &numberNode{value: tok.value}, nil
+	case tokenLParen:
+		inner, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, p.errorf(closing, tokenRParen.String())
+		}
+
+		return inner, nil
+	default:
+		return nil, p.errorf(tok, "number or '('")
+	}
+}
@@ -1,22 +1,58 @@
 package main
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"math/big"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/MarceloPetrucio/go-scalar-api-reference"
 
+	"github.com/NDOY3M4N/api-calculator/complexmath"
+	"github.com/NDOY3M4N/api-calculator/decimal"
+	"github.com/NDOY3M4N/api-calculator/expression"
+	"github.com/NDOY3M4N/api-calculator/linalg"
+	"github.com/NDOY3M4N/api-calculator/metrics"
 	"github.com/NDOY3M4N/api-calculator/repository"
+	"github.com/NDOY3M4N/api-calculator/scientific"
+	"github.com/NDOY3M4N/api-calculator/stats"
 )
 
 var (
 	ErrMissingBody  = errors.New("missing request body")
+	ErrBodyTooLarge = errors.New("request body is too large")
 	ErrDividyByZero = errors.New("division by zero is prohibited")
 	ErrLengthSum    = errors.New("provide at least 2 numbers")
+	ErrNotFinite    = errors.New("result is not a finite number")
+	ErrInvalidMode  = errors.New("invalid calculation mode")
+	ErrInvalidQuery = errors.New("invalid query parameter")
+
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+	ErrDatabaseTimeout = errors.New("the database did not answer in time")
+	ErrDatabaseBusy    = errors.New("the database is busy, retry later")
+	ErrRequestCanceled = errors.New("the request was canceled")
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Headers used to opt into the arbitrary-precision decimal and fraction modes
+const (
+	headerMode      = "X-Calc-Mode"
+	headerPrecision = "X-Calc-Precision"
+	headerRounding  = "X-Calc-Rounding"
 )
 
 type Payload struct {
@@ -26,40 +62,315 @@ type Payload struct {
 
 type PayloadSum []float64
 
+// DecimalString is a number kept as its decimal text. It can be sent either
+// as a JSON string ("0.1") or as a JSON number (0.1), in which case the
+// literal is kept as written.
+type DecimalString string
+
+func (d *DecimalString) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*d = DecimalString(s)
+
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*d = DecimalString(n)
+
+	return nil
+}
+
+type PayloadDecimal struct {
+	Number1 DecimalString `json:"number1" example:"0.1"`
+	Number2 DecimalString `json:"number2" example:"0.2"`
+}
+
+type PayloadSumDecimal []DecimalString
+
+type PayloadEvaluate struct {
+	Expression string `json:"expression" example:"(2 + 3) * 4 / 7"`
+}
+
+// PayloadNumber is the input of the scientific functions of one number.
+type PayloadNumber struct {
+	Number float64 `json:"number" example:"2"`
+}
+
+type PayloadPow struct {
+	Base     float64 `json:"base" example:"2"`
+	Exponent float64 `json:"exponent" example:"10"`
+}
+
+type PayloadRoot struct {
+	Number float64 `json:"number" example:"27"`
+	// Degree of the root, odd roots of negative numbers are real
+	Degree int `json:"degree" example:"3"`
+}
+
+type PayloadLog struct {
+	Number float64 `json:"number" example:"8"`
+	Base   float64 `json:"base" example:"2"`
+}
+
+type PayloadAngle struct {
+	Number float64 `json:"number" example:"90"`
+	// Unit of the angle given to sin, cos and tan, or returned by asin, acos
+	// and atan. Defaults to radians.
+	Unit scientific.Unit `json:"unit" enums:"radians,degrees" example:"degrees"`
+}
+
+type PayloadRound struct {
+	Number float64 `json:"number" example:"3.14159"`
+	// Digits after the decimal point, negative to round to tens, hundreds...
+	Digits int `json:"digits" example:"2"`
+}
+
+type PayloadStats struct {
+	Numbers []float64 `json:"numbers" example:"1,2,2,3,4"`
+	// Percentiles to compute, between 0 and 100
+	Percentiles []float64 `json:"percentiles" example:"25,90"`
+}
+
+type PayloadVectors struct {
+	A linalg.Vector `json:"a" example:"1,0,0"`
+	B linalg.Vector `json:"b" example:"0,1,0"`
+}
+
+type PayloadVector struct {
+	Vector linalg.Vector `json:"vector" example:"3,4"`
+}
+
+type PayloadMatrices struct {
+	A linalg.Matrix `json:"a"`
+	B linalg.Matrix `json:"b"`
+}
+
+type PayloadMatrix struct {
+	Matrix linalg.Matrix `json:"matrix"`
+}
+
+// PayloadSolve is the linear system Ax = b.
+type PayloadSolve struct {
+	A linalg.Matrix `json:"a"`
+	B linalg.Vector `json:"b" example:"8,-11,-3"`
+}
+
+// PayloadComplexPair holds two complex numbers, each given as {"re", "im"},
+// as an a+bi string such as "3-4i" or as a real number.
+type PayloadComplexPair struct {
+	Number1 complexmath.Number `json:"number1"`
+	Number2 complexmath.Number `json:"number2"`
+}
+
+type PayloadComplex struct {
+	Number complexmath.Number `json:"number"`
+}
+
+type PayloadComplexAngle struct {
+	Number complexmath.Number `json:"number"`
+	// Unit of the phase, defaults to radians
+	Unit scientific.Unit `json:"unit" enums:"radians,degrees" example:"degrees"`
+}
+
+type PayloadPolar struct {
+	Modulus float64 `json:"modulus" example:"5"`
+	Phase   float64 `json:"phase" example:"90"`
+	// Unit of the phase, defaults to radians
+	Unit scientific.Unit `json:"unit" enums:"radians,degrees" example:"degrees"`
+}
+
+type PayloadComplexPow struct {
+	Base     complexmath.Number `json:"base"`
+	Exponent complexmath.Number `json:"exponent"`
+}
+
+type PayloadComplexRoot struct {
+	Number complexmath.Number `json:"number"`
+	Degree int                `json:"degree" example:"3"`
+}
+
 type APIError struct {
 	Error string `json:"error"`
+	// Code tells why the inputs of a scientific function or of a complex
+	// operation, or the operands of a vector or matrix operation, are
+	// invalid, and is not_finite for any result that overflows
+	Code    string `json:"code,omitempty" example:"negative_root"`
+	Details any    `json:"details,omitempty"`
+	// RequestID is also returned in the X-Request-ID header
+	RequestID string `json:"request_id,omitempty"`
 }
 
 type APISuccess struct {
 	Result float64 `json:"result"`
 }
 
+type APIStatsSuccess struct {
+	Result stats.Summary `json:"result"`
+}
+
+type APIVectorSuccess struct {
+	Result linalg.Vector `json:"result" example:"0,0,1"`
+}
+
+type APIMatrixSuccess struct {
+	Result linalg.Matrix `json:"result"`
+}
+
+// APIComplexSuccess gives a complex result both by its parts and in the a+bi
+// form.
+type APIComplexSuccess struct {
+	Result complexmath.Number `json:"result"`
+	Text   string             `json:"text" example:"3+4i"`
+}
+
+type APIPolarSuccess struct {
+	Result complexmath.Polar `json:"result"`
+}
+
+type APIDecimalSuccess struct {
+	Result string `json:"result" example:"0.3"`
+}
+
+type APIFractionSuccess struct {
+	// Result is the exact value, as a reduced fraction
+	Result string `json:"result" example:"1/3"`
+	// Decimal is the result rounded as in the decimal mode
+	Decimal string `json:"decimal" example:"0.3333333333333333333333333333333333"`
+}
+
+type APIOperationsPage struct {
+	Data []repository.Operations `json:"data"`
+	// NextCursor is empty when there are no more operations to fetch
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
 type PayloadLogin struct {
-	Pseudo string `json:"pseudo" example:"p4p1"`
+	Pseudo   string `json:"pseudo" example:"p4p1"`
+	Password string `json:"password" example:"correct-horse-42"`
+}
+
+type PayloadRegister struct {
+	Pseudo   string `json:"pseudo" example:"p4p1"`
+	Password string `json:"password" example:"correct-horse-42"`
 }
 
 type APILoginSuccess struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	// ExpiresIn is the lifetime of the access token in seconds
+	ExpiresIn int `json:"expires_in" example:"900"`
+}
+
+type PayloadAPIKey struct {
+	Name   string   `json:"name" example:"nightly-batch"`
+	Scopes []string `json:"scopes" example:"math:add,math:sum"`
+}
+
+type APIKeyCreated struct {
+	repository.APIKey
+	// Key is only returned once, at creation
+	Key string `json:"key"`
+}
+
+type PayloadRole struct {
+	Role repository.Role `json:"role" example:"read-only"`
+}
+
+type PayloadRefresh struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 type Handler struct {
-	repo *repository.Repository
+	repo        repository.Store
+	revocations *RevocationList
 }
 
-func NewHandler(repo *repository.Repository) *Handler {
-	return &Handler{repo}
+func NewHandler(repo repository.Store, revocations *RevocationList) *Handler {
+	return &Handler{repo, revocations}
 }
 
 func (h *Handler) RegisterRoutes(router *http.ServeMux) http.HandlerFunc {
-	isAuth := IsAuthenticated(h.repo)
+	isAuth := Traced("IsAuthenticated", IsAuthenticated(h.repo, h.revocations))
 
-	router.HandleFunc("POST /login", h.loginHandler)
+	router.HandleFunc("POST /register", TraceHandler(h.registerHandler))
+	router.HandleFunc("POST /login", TraceHandler(h.loginHandler))
+	router.HandleFunc("POST /token/refresh", TraceHandler(h.refreshTokenHandler))
+
+	scoped := func(scope string, xs ...Middleware) Middleware {
+		// decimalMode may answer in place of the handler, so it is part of
+		// the handler span
+		stack := []Middleware{isAuth, Traced("RequireScope", RequireScope(scope)), TraceHandler}
+		return CreateStack(append(stack, xs...)...)
+	}
 
-	router.HandleFunc("POST /add", isAuth(h.addHandler))
-	router.HandleFunc("POST /sum", isAuth(h.sumHandler))
-	router.HandleFunc("POST /substract", isAuth(h.substractHandler))
-	router.HandleFunc("POST /multiply", isAuth(h.multiplyHandler))
-	router.HandleFunc("POST /divide", isAuth(h.divideHandler))
+	router.HandleFunc("POST /add", scoped(ScopeMathAdd, h.decimalMode(repository.TypeAdd))(h.addHandler))
+	router.HandleFunc("POST /sum", scoped(ScopeMathSum, h.decimalMode(repository.TypeSum))(h.sumHandler))
+	router.HandleFunc("POST /substract", scoped(ScopeMathSubstract, h.decimalMode(repository.TypeSubstract))(h.substractHandler))
+	router.HandleFunc("POST /multiply", scoped(ScopeMathMultiply, h.decimalMode(repository.TypeMultiply))(h.multiplyHandler))
+	router.HandleFunc("POST /divide", scoped(ScopeMathDivide, h.decimalMode(repository.TypeDivide))(h.divideHandler))
+	router.HandleFunc("POST /evaluate", scoped(ScopeMathEvaluate)(h.evaluateHandler))
+
+	router.HandleFunc("POST /pow", scoped(ScopeMathScientific)(h.powHandler))
+	router.HandleFunc("POST /sqrt", scoped(ScopeMathScientific)(h.numberHandler(repository.TypeSqrt, scientific.Sqrt)))
+	router.HandleFunc("POST /root", scoped(ScopeMathScientific)(h.rootHandler))
+	router.HandleFunc("POST /exp", scoped(ScopeMathScientific)(h.numberHandler(repository.TypeExp, scientific.Exp)))
+	router.HandleFunc("POST /ln", scoped(ScopeMathScientific)(h.numberHandler(repository.TypeLn, scientific.Ln)))
+	router.HandleFunc("POST /log10", scoped(ScopeMathScientific)(h.numberHandler(repository.TypeLog10, scientific.Log10)))
+	router.HandleFunc("POST /log", scoped(ScopeMathScientific)(h.logHandler))
+	router.HandleFunc("POST /sin", scoped(ScopeMathScientific)(h.angleHandler(repository.TypeSin, scientific.Sin)))
+	router.HandleFunc("POST /cos", scoped(ScopeMathScientific)(h.angleHandler(repository.TypeCos, scientific.Cos)))
+	router.HandleFunc("POST /tan", scoped(ScopeMathScientific)(h.angleHandler(repository.TypeTan, scientific.Tan)))
+	router.HandleFunc("POST /asin", scoped(ScopeMathScientific)(h.angleHandler(repository.TypeAsin, scientific.Asin)))
+	router.HandleFunc("POST /acos", scoped(ScopeMathScientific)(h.angleHandler(repository.TypeAcos, scientific.Acos)))
+	router.HandleFunc("POST /atan", scoped(ScopeMathScientific)(h.angleHandler(repository.TypeAtan, scientific.Atan)))
+	router.HandleFunc("POST /abs", scoped(ScopeMathScientific)(h.numberHandler(repository.TypeAbs, scientific.Abs)))
+	router.HandleFunc("POST /floor", scoped(ScopeMathScientific)(h.numberHandler(repository.TypeFloor, scientific.Floor)))
+	router.HandleFunc("POST /ceil", scoped(ScopeMathScientific)(h.numberHandler(repository.TypeCeil, scientific.Ceil)))
+	router.HandleFunc("POST /round", scoped(ScopeMathScientific)(h.roundHandler))
+
+	router.HandleFunc("POST /stats", scoped(ScopeMathStats)(h.statsHandler))
+
+	router.HandleFunc("POST /vectors/dot", scoped(ScopeMathLinalg)(h.dotHandler))
+	router.HandleFunc("POST /vectors/cross", scoped(ScopeMathLinalg)(h.crossHandler))
+	router.HandleFunc("POST /vectors/norm", scoped(ScopeMathLinalg)(h.normHandler))
+	router.HandleFunc("POST /matrices/add", scoped(ScopeMathLinalg)(h.matricesHandler(repository.TypeMatrixAdd, linalg.Add)))
+	router.HandleFunc("POST /matrices/multiply", scoped(ScopeMathLinalg)(h.matricesHandler(repository.TypeMatrixMultiply, linalg.Multiply)))
+	router.HandleFunc("POST /matrices/transpose", scoped(ScopeMathLinalg)(h.matrixHandler(repository.TypeMatrixTranspose, linalg.Transpose)))
+	router.HandleFunc("POST /matrices/inverse", scoped(ScopeMathLinalg)(h.matrixHandler(repository.TypeMatrixInverse, linalg.Inverse)))
+	router.HandleFunc("POST /matrices/determinant", scoped(ScopeMathLinalg)(h.determinantHandler))
+	router.HandleFunc("POST /matrices/solve", scoped(ScopeMathLinalg)(h.solveHandler))
+
+	router.HandleFunc("POST /complex/add", scoped(ScopeMathComplex)(h.complexPairHandler(repository.TypeComplexAdd, complexmath.Add)))
+	router.HandleFunc("POST /complex/substract", scoped(ScopeMathComplex)(h.complexPairHandler(repository.TypeComplexSubstract, complexmath.Sub)))
+	router.HandleFunc("POST /complex/multiply", scoped(ScopeMathComplex)(h.complexPairHandler(repository.TypeComplexMultiply, complexmath.Mul)))
+	router.HandleFunc("POST /complex/divide", scoped(ScopeMathComplex)(h.complexPairHandler(repository.TypeComplexDivide, complexmath.Div)))
+	router.HandleFunc("POST /complex/magnitude", scoped(ScopeMathComplex)(h.complexMagnitudeHandler))
+	router.HandleFunc("POST /complex/phase", scoped(ScopeMathComplex)(h.complexPhaseHandler))
+	router.HandleFunc("POST /complex/conjugate", scoped(ScopeMathComplex)(h.complexConjugateHandler))
+	router.HandleFunc("POST /complex/polar", scoped(ScopeMathComplex)(h.complexPolarHandler))
+	router.HandleFunc("POST /complex/rectangular", scoped(ScopeMathComplex)(h.complexRectangularHandler))
+	router.HandleFunc("POST /complex/pow", scoped(ScopeMathComplex)(h.complexPowHandler))
+	router.HandleFunc("POST /complex/root", scoped(ScopeMathComplex)(h.complexRootHandler))
+
+	router.HandleFunc("GET /operations", scoped(ScopeHistoryRead)(h.listOperationsHandler))
+	router.HandleFunc("GET /operations/{id}", scoped(ScopeHistoryRead)(h.getOperationHandler))
+
+	router.HandleFunc("POST /logout", isAuth(TraceHandler(h.logoutHandler)))
+	router.HandleFunc("DELETE /users/{id}/sessions", isAuth(TraceHandler(h.revokeSessionsHandler)))
+
+	router.HandleFunc("POST /api-keys", scoped(ScopeKeysManage)(h.createAPIKeyHandler))
+	router.HandleFunc("GET /api-keys", scoped(ScopeKeysManage)(h.listAPIKeysHandler))
+	router.HandleFunc("DELETE /api-keys/{id}", scoped(ScopeKeysManage)(h.revokeAPIKeyHandler))
+
+	router.HandleFunc("GET /users", scoped(ScopeUsersAdmin)(h.listUsersHandler))
+	router.HandleFunc("PUT /users/{id}/role", scoped(ScopeUsersAdmin)(h.updateUserRoleHandler))
 
 	// Define a separate handler for the /scalar endpoint
 	scalarHandler := http.StripPrefix(
@@ -82,11 +393,14 @@ func (h *Handler) RegisterRoutes(router *http.ServeMux) http.HandlerFunc {
 	)
 
 	v1 := http.NewServeMux()
-	v1.Handle("/api/v1/", http.StripPrefix("/api/v1", router))
+	v1.Handle("/api/v1/", http.StripPrefix("/api/v1", recordRoute("/api/v1", router)))
 
 	// Combine the v1 handler and the Scalar handler
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/docs" || strings.HasPrefix(r.URL.Path, "/docs") {
+			if info, ok := r.Context().Value(infoKey).(*requestInfo); ok {
+				info.route = "/docs"
+			}
 			scalarHandler.ServeHTTP(w, r)
 		} else {
 			v1.ServeHTTP(w, r)
@@ -96,277 +410,2023 @@ func (h *Handler) RegisterRoutes(router *http.ServeMux) http.HandlerFunc {
 	return handler
 }
 
-// Login
+// Register
 //
-// @summary Login
-// @description Log the user
+// @summary Register
+// @description Create a user with a password
 // @tags User
 // @accept json
 // @produce json
-// @param payload body PayloadLogin true "Field needed for login"
-// @success 200 {object} APILoginSuccess
+// @param payload body PayloadRegister true "Fields needed for registration"
+// @success 201 {object} repository.User
 // @failure 400 {object} APIError
-// @router /login [post]
-func (h *Handler) loginHandler(w http.ResponseWriter, r *http.Request) {
-	var payload PayloadLogin
-	if err := decodeJSON(r, &payload); err != nil {
+// @failure 409 {object} APIError
+// @router /register [post]
+func (h *Handler) registerHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadRegister
+	if err := decodeJSON(w, r, &payload); err != nil {
 		writeError(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	if payload.Pseudo == "" {
-		writeError(w, r, http.StatusBadRequest, fmt.Errorf("pseudo should not be empty"))
+	if err := validatePseudo(payload.Pseudo); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	user, err := h.repo.FindUserByPseudo(payload.Pseudo)
-	if err != nil {
-		writeError(w, r, http.StatusBadRequest, fmt.Errorf("error finding user by pseudo"))
+	if err := validatePassword(payload.Pseudo, payload.Password); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	token, err := GenerateToken(int(user.Id))
+	hash, err := hashPassword(payload.Password)
 	if err != nil {
-		writeError(w, r, http.StatusBadRequest, fmt.Errorf("error generating token"))
+		writeError(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
-	reqID := r.Context().Value(requestIDKey).(string)
-
-	logger.Info("Request successful",
-		slog.Int("statusCode", http.StatusOK),
-		slog.String("remoteAddr", r.RemoteAddr),
-		slog.Group("request",
-			slog.String("id", reqID),
-			slog.String("method", r.Method),
-			slog.String("path", r.URL.Path),
-		),
-	)
+	user, err := h.repo.CreateUser(r.Context(), payload.Pseudo, hash)
+	if err != nil {
+		if errors.Is(err, repository.ErrPseudoTaken) {
+			writeError(w, r, http.StatusConflict, err)
+			return
+		}
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
 
-	encodeJSON(w, http.StatusOK, map[string]string{"token": token})
+	writeResult(w, r, http.StatusCreated, user)
 }
 
-// Add two numbers
+// Login
 //
-// @summary Add two numbers
-// @description Add two numbers together
-// @tags Math
+// @summary Login
+// @description Log the user in with their pseudo and password. The account is
+// @description locked for a while after too many failed attempts.
+// @tags User
 // @accept json
 // @produce json
-// @param payload body Payload true "Numbers needed for the operation"
-// @Security BearerAuth
-// @success 200 {object} APISuccess
+// @param payload body PayloadLogin true "Field needed for login"
+// @success 200 {object} APILoginSuccess
 // @failure 400 {object} APIError
-// @router /add [post]
-func (h *Handler) addHandler(w http.ResponseWriter, r *http.Request) {
-	var payload Payload
-	if err := decodeJSON(r, &payload); err != nil {
+// @failure 401 {object} APIError
+// @failure 423 {object} APIError
+// @router /login [post]
+func (h *Handler) loginHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadLogin
+	if err := decodeJSON(w, r, &payload); err != nil {
 		writeError(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	userID := r.Context().Value(userIDKey).(int)
+	if payload.Pseudo == "" || payload.Password == "" {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("pseudo and password should not be empty"))
+		return
+	}
 
-	result := payload.Number1 + payload.Number2
-	param := repository.AddOperationParams{
-		Inputs: []float64{float64(payload.Number1), float64(payload.Number2)},
-		Type:   repository.TypeAdd,
-		Result: float64(result),
-		UserId: userID,
+	user, err := h.repo.FindUserByPseudo(r.Context(), payload.Pseudo)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			// Spend as much time as for an existing user
+			checkPassword("", payload.Password)
+			metrics.AuthFailures.WithLabelValues(metrics.ReasonInvalidPassword).Inc()
+			writeError(w, r, http.StatusUnauthorized, ErrInvalidCredentials)
+			return
+		}
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		metrics.AuthFailures.WithLabelValues(metrics.ReasonAccountLocked).Inc()
+		w.Header().Set("Retry-After", fmt.Sprintf("%d", int(time.Until(*user.LockedUntil).Seconds())+1))
+		writeError(w, r, http.StatusLocked, ErrAccountLocked)
+		return
+	}
+
+	if !checkPassword(user.PasswordHash, payload.Password) {
+		metrics.AuthFailures.WithLabelValues(metrics.ReasonInvalidPassword).Inc()
+		if err := h.repo.RecordLoginFailure(r.Context(), user.Id, maxLoginAttempts, lockoutDuration); err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		writeError(w, r, http.StatusUnauthorized, ErrInvalidCredentials)
+		return
+	}
+
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		if err := h.repo.ResetLoginFailures(r.Context(), user.Id); err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
 	}
 
-	if err := h.repo.AddOperation(param); err != nil {
+	tokens, refreshTokenParam, err := issueTokens(user, "")
+	if err != nil {
 		writeError(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
-	writeSuccess(w, r, http.StatusOK, result)
+	if err := h.repo.CreateRefreshToken(r.Context(), refreshTokenParam); err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeResult(w, r, http.StatusOK, tokens)
 }
 
-// Sum numbers
+// Refresh the access token
 //
-// @summary Sum numbers
-// @description Add all numbers in an array
-// @tags Math
+// @summary Refresh the access token
+// @description Exchange a refresh token for a new access token and a new
+// @description refresh token. A refresh token can only be used once: using
+// @description it again revokes every token obtained from the same login.
+// @tags User
 // @accept json
 // @produce json
-// @param payload body PayloadSum true "Array of numbers needed for the operation"
-// @Security BearerAuth
-// @success 200 {object} APISuccess
+// @param payload body PayloadRefresh true "Refresh token received at login"
+// @success 200 {object} APILoginSuccess
 // @failure 400 {object} APIError
-// @router /sum [post]
-func (h *Handler) sumHandler(w http.ResponseWriter, r *http.Request) {
-	var payload PayloadSum
-	if err := decodeJSON(r, &payload); err != nil {
+// @failure 401 {object} APIError
+// @router /token/refresh [post]
+func (h *Handler) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadRefresh
+	if err := decodeJSON(w, r, &payload); err != nil {
 		writeError(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	if len(payload) < 2 {
-		writeError(w, r, http.StatusBadRequest, ErrLengthSum)
+	if payload.RefreshToken == "" {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("refresh_token should not be empty"))
 		return
 	}
 
-	var result float64
-	for _, num := range payload {
-		result += num
+	current, err := h.repo.FindRefreshToken(r.Context(), HashRefreshToken(payload.RefreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			metrics.AuthFailures.WithLabelValues(metrics.ReasonInvalidRefresh).Inc()
+			writeError(w, r, http.StatusUnauthorized, ErrInvalidRefreshToken)
+			return
+		}
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
 	}
 
-	userID := r.Context().Value(userIDKey).(int)
+	if time.Now().After(current.ExpiresAt) {
+		metrics.AuthFailures.WithLabelValues(metrics.ReasonInvalidRefresh).Inc()
+		writeError(w, r, http.StatusUnauthorized, ErrInvalidRefreshToken)
+		return
+	}
 
-	param := repository.AddOperationParams{
-		Inputs: payload,
-		Type:   repository.TypeSum,
-		Result: float64(result),
-		UserId: userID,
+	user, err := h.repo.FindUserById(r.Context(), int(current.UserId))
+	if err != nil {
+		metrics.AuthFailures.WithLabelValues(metrics.ReasonUnknownUser).Inc()
+		writeError(w, r, http.StatusUnauthorized, ErrInvalidRefreshToken)
+		return
 	}
 
-	if err := h.repo.AddOperation(param); err != nil {
+	tokens, next, err := issueTokens(user, current.FamilyId)
+	if err != nil {
 		writeError(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
-	writeSuccess(w, r, http.StatusOK, result)
+	err = h.repo.RotateRefreshToken(r.Context(), current.Id, next)
+	if errors.Is(err, repository.ErrRefreshTokenUsed) {
+		// The token was stolen or leaked: whoever rotated it first cannot be
+		// told apart from the legitimate user, so the whole family goes
+		metrics.AuthFailures.WithLabelValues(metrics.ReasonInvalidRefresh).Inc()
+		if err := h.repo.RevokeRefreshTokenFamily(r.Context(), current.FamilyId); err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		writeError(w, r, http.StatusUnauthorized, ErrInvalidRefreshToken)
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeResult(w, r, http.StatusOK, tokens)
 }
 
-// Substract two numbers
+// Logout
 //
-// @summary Substract two numbers
-// @description Substract two numbers together
-// @tags Math
+// @summary Logout
+// @description Revoke the access token used for this request and, when it is
+// @description given, the refresh token obtained with it
+// @tags User
 // @accept json
-// @produce json
-// @param payload body Payload true "Numbers needed for the operation"
+// @param payload body PayloadRefresh false "Refresh token to revoke as well"
 // @Security BearerAuth
-// @success 200 {object} APISuccess
+// @success 204
 // @failure 400 {object} APIError
-// @router /substract [post]
-func (h *Handler) substractHandler(w http.ResponseWriter, r *http.Request) {
-	var payload Payload
-	if err := decodeJSON(r, &payload); err != nil {
-		writeError(w, r, http.StatusBadRequest, err)
-		return
+// @router /logout [post]
+func (h *Handler) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadRefresh
+	if r.ContentLength != 0 {
+		if err := decodeJSON(w, r, &payload); err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
 	}
 
-	result := payload.Number1 - payload.Number2
-
 	userID := r.Context().Value(userIDKey).(int)
-
-	param := repository.AddOperationParams{
-		Inputs: []float64{float64(payload.Number1), float64(payload.Number2)},
-		Type:   repository.TypeSubstract,
-		Result: float64(result),
-		UserId: userID,
+	claims, ok := r.Context().Value(claimsKey).(*Claims)
+	if !ok {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("logout requires a bearer token, revoke api keys instead"))
+		return
 	}
 
-	if err := h.repo.AddOperation(param); err != nil {
+	if err := h.revocations.Revoke(r.Context(), claims.ID, userID, claims.ExpiresAt.Time); err != nil {
 		writeError(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
-	writeSuccess(w, r, http.StatusOK, result)
+	if payload.RefreshToken != "" {
+		token, err := h.repo.FindRefreshToken(r.Context(), HashRefreshToken(payload.RefreshToken))
+		if err != nil && !errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		if token != nil && token.UserId == int64(userID) {
+			if err := h.repo.RevokeRefreshTokenFamily(r.Context(), token.FamilyId); err != nil {
+				writeError(w, r, http.StatusInternalServerError, err)
+				return
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
-// Multiply two numbers
+// Revoke all sessions of a user
 //
-// @summary Multiply two numbers
-// @description Multiply two numbers together
-// @tags Math
-// @accept json
-// @produce json
-// @param payload body Payload true "Numbers needed for the operation"
+// @summary Revoke all sessions of a user
+// @description Revoke every access and refresh token of a user, e.g. after a
+// @description device was lost. Revoking the sessions of another user requires
+// @description the users:admin scope.
+// @tags User
+// @param id path int true "User ID"
 // @Security BearerAuth
-// @success 200 {object} APISuccess
-// @failure 400 {object} APIError
-// @router /multiply [post]
-func (h *Handler) multiplyHandler(w http.ResponseWriter, r *http.Request) {
-	var payload Payload
-	if err := decodeJSON(r, &payload); err != nil {
-		writeError(w, r, http.StatusBadRequest, err)
+// @Security ApiKeyAuth
+// @success 204
+// @failure 403 {object} APIError
+// @failure 404 {object} APIError
+// @router /users/{id}/sessions [delete]
+func (h *Handler) revokeSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("%w: id", ErrInvalidQuery))
 		return
 	}
 
-	result := payload.Number1 * payload.Number2
-
 	userID := r.Context().Value(userIDKey).(int)
-
-	param := repository.AddOperationParams{
-		Inputs: []float64{float64(payload.Number1), float64(payload.Number2)},
-		Type:   repository.TypeMultiply,
-		Result: float64(result),
-		UserId: userID,
+	if id != userID && !hasScope(r, ScopeUsersAdmin) {
+		writeError(w, r, http.StatusForbidden, &MissingScopeError{ScopeUsersAdmin})
+		return
 	}
 
-	if err := h.repo.AddOperation(param); err != nil {
+	if err := h.repo.RevokeUserSessions(r.Context(), id, sessionsCutoff()); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			writeError(w, r, http.StatusNotFound, err)
+			return
+		}
 		writeError(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
-	writeSuccess(w, r, http.StatusOK, result)
+	w.WriteHeader(http.StatusNoContent)
 }
 
-// divideHandler Foo
+// Create an API key
 //
-// @summary Divide two numbers
-// @description Divide two numbers together
-// @tags Math
+// @summary Create an API key
+// @description Create a long-lived key to send in the X-API-Key header. The
+// @description key is only shown in this response.
+// @tags API keys
 // @accept json
 // @produce json
-// @param payload body Payload true "Numbers needed for the operation"
+// @param payload body PayloadAPIKey true "Name and scopes of the key"
 // @Security BearerAuth
-// @success 200 {object} APISuccess
+// @Security ApiKeyAuth
+// @success 201 {object} APIKeyCreated
 // @failure 400 {object} APIError
-// @router /divide [post]
-func (h *Handler) divideHandler(w http.ResponseWriter, r *http.Request) {
-	var payload Payload
-	if err := decodeJSON(r, &payload); err != nil {
+// @router /api-keys [post]
+func (h *Handler) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadAPIKey
+	if err := decodeJSON(w, r, &payload); err != nil {
 		writeError(w, r, http.StatusBadRequest, err)
 		return
 	}
 
-	if payload.Number2 == 0 {
-		writeError(w, r, http.StatusBadRequest, ErrDividyByZero)
+	payload.Name = strings.TrimSpace(payload.Name)
+	if payload.Name == "" || len(payload.Name) > 64 {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("name should be between 1 and 64 characters"))
 		return
 	}
 
-	result := payload.Number1 / payload.Number2
-
-	userID := r.Context().Value(userIDKey).(int)
-
-	param := repository.AddOperationParams{
-		Inputs: []float64{float64(payload.Number1), float64(payload.Number2)},
-		Type:   repository.TypeDivide,
-		Result: float64(result),
-		UserId: userID,
+	scopes, err := normalizeScopes(payload.Scopes)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
 	}
 
-	if err := h.repo.AddOperation(param); err != nil {
+	for _, scope := range scopes {
+		if !hasScope(r, scope) {
+			writeError(w, r, http.StatusForbidden, &MissingScopeError{scope})
+			return
+		}
+	}
+
+	key, prefix, hash, err := GenerateAPIKey()
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	userID := r.Context().Value(userIDKey).(int)
+
+	apiKey, err := h.repo.CreateAPIKey(r.Context(), repository.CreateAPIKeyParams{
+		UserId:  userID,
+		Name:    payload.Name,
+		Prefix:  prefix,
+		KeyHash: hash,
+		Scopes:  scopes,
+	})
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeResult(w, r, http.StatusCreated, APIKeyCreated{*apiKey, key})
+}
+
+// List API keys
+//
+// @summary List API keys
+// @description List the API keys of the authenticated user, revoked ones included
+// @tags API keys
+// @produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {array} repository.APIKey
+// @router /api-keys [get]
+func (h *Handler) listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(userIDKey).(int)
+
+	keys, err := h.repo.ListAPIKeys(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeResult(w, r, http.StatusOK, keys)
+}
+
+// Revoke an API key
+//
+// @summary Revoke an API key
+// @description Revoke an API key of the authenticated user
+// @tags API keys
+// @param id path int true "API key ID"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 204
+// @failure 404 {object} APIError
+// @router /api-keys/{id} [delete]
+func (h *Handler) revokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("%w: id", ErrInvalidQuery))
+		return
+	}
+
+	userID := r.Context().Value(userIDKey).(int)
+
+	if err := h.repo.RevokeAPIKey(r.Context(), userID, id); err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			writeError(w, r, http.StatusNotFound, err)
+			return
+		}
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	verifiedAPIKeys.forgetID(id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// List users
+//
+// @summary List users
+// @description List every user along with their role
+// @tags Admin
+// @produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {array} repository.User
+// @failure 403 {object} APIError
+// @router /users [get]
+func (h *Handler) listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	users, err := h.repo.ListUsers(r.Context())
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeResult(w, r, http.StatusOK, users)
+}
+
+// Change the role of a user
+//
+// @summary Change the role of a user
+// @description Change the role of a user. Their sessions are revoked so that
+// @description tokens carrying the previous role stop working.
+// @tags Admin
+// @accept json
+// @param id path int true "User ID"
+// @param payload body PayloadRole true "New role"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 204
+// @failure 400 {object} APIError
+// @failure 403 {object} APIError
+// @failure 404 {object} APIError
+// @router /users/{id}/role [put]
+func (h *Handler) updateUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("%w: id", ErrInvalidQuery))
+		return
+	}
+
+	var payload PayloadRole
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := validateRole(payload.Role); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.repo.UpdateUserRole(r.Context(), id, payload.Role); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			writeError(w, r, http.StatusNotFound, err)
+			return
+		}
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.repo.RevokeUserSessions(r.Context(), id, sessionsCutoff()); err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sessionsCutoff is the date before which the tokens of a user whose
+// sessions are revoked now are rejected. Tokens carry their issue date with a
+// one second resolution, so tokens issued during the current second are
+// revoked as well.
+func sessionsCutoff() time.Time {
+	return time.Now().Truncate(time.Second).Add(time.Second)
+}
+
+// issueTokens creates an access token and a refresh token for user. The
+// refresh token joins familyID, or starts a new family when it is empty.
+func issueTokens(user *repository.User, familyID string) (APILoginSuccess, repository.CreateRefreshTokenParams, error) {
+	var param repository.CreateRefreshTokenParams
+
+	accessToken, err := GenerateToken(int(user.Id), user.Role)
+	if err != nil {
+		return APILoginSuccess{}, param, fmt.Errorf("error generating token")
+	}
+
+	refreshToken, hash, err := GenerateRefreshToken()
+	if err != nil {
+		return APILoginSuccess{}, param, err
+	}
+
+	if familyID == "" {
+		if familyID, err = randomToken(16); err != nil {
+			return APILoginSuccess{}, param, err
+		}
+	}
+
+	param = repository.CreateRefreshTokenParams{
+		UserId:    user.Id,
+		TokenHash: hash,
+		FamilyId:  familyID,
+		ExpiresAt: time.Now().Add(conf.JWT.RefreshTTL),
+	}
+
+	tokens := APILoginSuccess{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(conf.JWT.AccessTTL.Seconds()),
+	}
+
+	return tokens, param, nil
+}
+
+// Add two numbers
+//
+// @summary Add two numbers
+// @description Add two numbers together
+// @tags Math
+// @accept json
+// @produce json
+// @param payload body Payload true "Numbers needed for the operation"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APISuccess
+// @failure 400 {object} APIError
+// @param X-Calc-Mode header string false "Set to \"decimal\" to compute exactly on decimal strings, or to \"fraction\" on fractions such as \"1/3\"" Enums(float, decimal, fraction)
+// @param X-Calc-Precision header int false "Significant digits of a decimal result, 0 for exact"
+// @param X-Calc-Rounding header string false "Rounding mode of a decimal result" Enums(half_even, half_up, half_down, up, down, ceiling, floor)
+// @success 200 {object} APIDecimalSuccess "Result in decimal mode"
+// @success 200 {object} APIFractionSuccess "Result in fraction mode"
+// @router /add [post]
+func (h *Handler) addHandler(w http.ResponseWriter, r *http.Request) {
+	var payload Payload
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	userID := r.Context().Value(userIDKey).(int)
+
+	result := payload.Number1 + payload.Number2
+	if math.IsInf(result, 0) {
+		writeError(w, r, http.StatusBadRequest, ErrNotFinite)
+		return
+	}
+	param := repository.AddOperationParams{
+		Inputs:    formatFloats(payload.Number1, payload.Number2),
+		Type:      repository.TypeAdd,
+		Result:    formatFloat(result),
+		UserId:    userID,
+		RequestId: requestID(r),
+	}
+
+	if err := h.repo.AddOperation(r.Context(), param); err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeSuccess(w, r, http.StatusOK, result)
+}
+
+// Sum numbers
+//
+// @summary Sum numbers
+// @description Add all numbers in an array
+// @tags Math
+// @accept json
+// @produce json
+// @param payload body PayloadSum true "Array of numbers needed for the operation"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APISuccess
+// @failure 400 {object} APIError
+// @param X-Calc-Mode header string false "Set to \"decimal\" to compute exactly on decimal strings, or to \"fraction\" on fractions such as \"1/3\"" Enums(float, decimal, fraction)
+// @param X-Calc-Precision header int false "Significant digits of a decimal result, 0 for exact"
+// @param X-Calc-Rounding header string false "Rounding mode of a decimal result" Enums(half_even, half_up, half_down, up, down, ceiling, floor)
+// @success 200 {object} APIDecimalSuccess "Result in decimal mode"
+// @success 200 {object} APIFractionSuccess "Result in fraction mode"
+// @router /sum [post]
+func (h *Handler) sumHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadSum
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(payload) < 2 {
+		writeError(w, r, http.StatusBadRequest, ErrLengthSum)
+		return
+	}
+
+	var result float64
+	for _, num := range payload {
+		result += num
+	}
+	if math.IsInf(result, 0) {
+		writeError(w, r, http.StatusBadRequest, ErrNotFinite)
+		return
+	}
+
+	userID := r.Context().Value(userIDKey).(int)
+
+	param := repository.AddOperationParams{
+		Inputs:    formatFloats(payload...),
+		Type:      repository.TypeSum,
+		Result:    formatFloat(result),
+		UserId:    userID,
+		RequestId: requestID(r),
+	}
+
+	if err := h.repo.AddOperation(r.Context(), param); err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeSuccess(w, r, http.StatusOK, result)
+}
+
+// Substract two numbers
+//
+// @summary Substract two numbers
+// @description Substract two numbers together
+// @tags Math
+// @accept json
+// @produce json
+// @param payload body Payload true "Numbers needed for the operation"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APISuccess
+// @failure 400 {object} APIError
+// @param X-Calc-Mode header string false "Set to \"decimal\" to compute exactly on decimal strings, or to \"fraction\" on fractions such as \"1/3\"" Enums(float, decimal, fraction)
+// @param X-Calc-Precision header int false "Significant digits of a decimal result, 0 for exact"
+// @param X-Calc-Rounding header string false "Rounding mode of a decimal result" Enums(half_even, half_up, half_down, up, down, ceiling, floor)
+// @success 200 {object} APIDecimalSuccess "Result in decimal mode"
+// @success 200 {object} APIFractionSuccess "Result in fraction mode"
+// @router /substract [post]
+func (h *Handler) substractHandler(w http.ResponseWriter, r *http.Request) {
+	var payload Payload
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	result := payload.Number1 - payload.Number2
+	if math.IsInf(result, 0) {
+		writeError(w, r, http.StatusBadRequest, ErrNotFinite)
+		return
+	}
+
+	userID := r.Context().Value(userIDKey).(int)
+
+	param := repository.AddOperationParams{
+		Inputs:    formatFloats(payload.Number1, payload.Number2),
+		Type:      repository.TypeSubstract,
+		Result:    formatFloat(result),
+		UserId:    userID,
+		RequestId: requestID(r),
+	}
+
+	if err := h.repo.AddOperation(r.Context(), param); err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeSuccess(w, r, http.StatusOK, result)
+}
+
+// Multiply two numbers
+//
+// @summary Multiply two numbers
+// @description Multiply two numbers together
+// @tags Math
+// @accept json
+// @produce json
+// @param payload body Payload true "Numbers needed for the operation"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APISuccess
+// @failure 400 {object} APIError
+// @param X-Calc-Mode header string false "Set to \"decimal\" to compute exactly on decimal strings, or to \"fraction\" on fractions such as \"1/3\"" Enums(float, decimal, fraction)
+// @param X-Calc-Precision header int false "Significant digits of a decimal result, 0 for exact"
+// @param X-Calc-Rounding header string false "Rounding mode of a decimal result" Enums(half_even, half_up, half_down, up, down, ceiling, floor)
+// @success 200 {object} APIDecimalSuccess "Result in decimal mode"
+// @success 200 {object} APIFractionSuccess "Result in fraction mode"
+// @router /multiply [post]
+func (h *Handler) multiplyHandler(w http.ResponseWriter, r *http.Request) {
+	var payload Payload
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	result := payload.Number1 * payload.Number2
+	if math.IsInf(result, 0) {
+		writeError(w, r, http.StatusBadRequest, ErrNotFinite)
+		return
+	}
+
+	userID := r.Context().Value(userIDKey).(int)
+
+	param := repository.AddOperationParams{
+		Inputs:    formatFloats(payload.Number1, payload.Number2),
+		Type:      repository.TypeMultiply,
+		Result:    formatFloat(result),
+		UserId:    userID,
+		RequestId: requestID(r),
+	}
+
+	if err := h.repo.AddOperation(r.Context(), param); err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeSuccess(w, r, http.StatusOK, result)
+}
+
+// divideHandler Foo
+//
+// @summary Divide two numbers
+// @description Divide two numbers together
+// @tags Math
+// @accept json
+// @produce json
+// @param payload body Payload true "Numbers needed for the operation"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APISuccess
+// @failure 400 {object} APIError
+// @param X-Calc-Mode header string false "Set to \"decimal\" to compute exactly on decimal strings, or to \"fraction\" on fractions such as \"1/3\"" Enums(float, decimal, fraction)
+// @param X-Calc-Precision header int false "Significant digits of a decimal result, 0 for exact"
+// @param X-Calc-Rounding header string false "Rounding mode of a decimal result" Enums(half_even, half_up, half_down, up, down, ceiling, floor)
+// @success 200 {object} APIDecimalSuccess "Result in decimal mode"
+// @success 200 {object} APIFractionSuccess "Result in fraction mode"
+// @router /divide [post]
+func (h *Handler) divideHandler(w http.ResponseWriter, r *http.Request) {
+	var payload Payload
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if payload.Number2 == 0 {
+		writeError(w, r, http.StatusBadRequest, ErrDividyByZero)
+		return
+	}
+
+	result := payload.Number1 / payload.Number2
+	if math.IsInf(result, 0) {
+		writeError(w, r, http.StatusBadRequest, ErrNotFinite)
+		return
+	}
+
+	userID := r.Context().Value(userIDKey).(int)
+
+	param := repository.AddOperationParams{
+		Inputs:    formatFloats(payload.Number1, payload.Number2),
+		Type:      repository.TypeDivide,
+		Result:    formatFloat(result),
+		UserId:    userID,
+		RequestId: requestID(r),
+	}
+
+	if err := h.repo.AddOperation(r.Context(), param); err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeSuccess(w, r, http.StatusOK, result)
+}
+
+// Evaluate an expression
+//
+// @summary Evaluate an expression
+// @description Evaluate an infix expression using +, -, *, /, ^ and parentheses
+// @tags Math
+// @accept json
+// @produce json
+// @param payload body PayloadEvaluate true "Expression to evaluate"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APISuccess
+// @failure 400 {object} APIError
+// @router /evaluate [post]
+func (h *Handler) evaluateHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadEvaluate
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	expr, err := expression.Parse(payload.Expression)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := expr.Eval()
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	userID := r.Context().Value(userIDKey).(int)
+
+	param := repository.AddOperationParams{
+		Inputs:     formatFloats(expr.Numbers()...),
+		Type:       repository.TypeEvaluate,
+		Result:     formatFloat(result),
+		UserId:     userID,
+		Expression: payload.Expression,
+		RequestId:  requestID(r),
+	}
+
+	if err := h.repo.AddOperation(r.Context(), param); err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeSuccess(w, r, http.StatusOK, result)
+}
+
+// Raise to a power
+//
+// @summary Raise to a power
+// @description Raise a base to an exponent. Zero cannot be raised to a negative power, nor a negative base to a fractional one.
+// @tags Scientific
+// @accept json
+// @produce json
+// @param payload body PayloadPow true "Base and exponent"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APISuccess
+// @failure 400 {object} APIError
+// @router /pow [post]
+func (h *Handler) powHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadPow
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := scientific.Pow(payload.Base, payload.Exponent)
+	h.writeScientific(w, r, repository.TypePow, result, err, payload.Base, payload.Exponent)
+}
+
+// Compute the root of a number
+//
+// @summary Compute the root of a number
+// @description Compute the root of any non-zero degree of a number. Even roots of negative numbers are rejected.
+// @tags Scientific
+// @accept json
+// @produce json
+// @param payload body PayloadRoot true "Number and degree of the root"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APISuccess
+// @failure 400 {object} APIError
+// @router /root [post]
+func (h *Handler) rootHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadRoot
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := scientific.Root(payload.Number, payload.Degree)
+	h.writeScientific(w, r, repository.TypeRoot, result, err, payload.Number, float64(payload.Degree))
+}
+
+// Compute a logarithm
+//
+// @summary Compute a logarithm
+// @description Compute the logarithm of a positive number in a positive base other than 1
+// @tags Scientific
+// @accept json
+// @produce json
+// @param payload body PayloadLog true "Number and base of the logarithm"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APISuccess
+// @failure 400 {object} APIError
+// @router /log [post]
+func (h *Handler) logHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadLog
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := scientific.Log(payload.Number, payload.Base)
+	h.writeScientific(w, r, repository.TypeLog, result, err, payload.Number, payload.Base)
+}
+
+// Round a number
+//
+// @summary Round a number
+// @description Round a number half away from zero to a number of decimals, between -15 and 15
+// @tags Scientific
+// @accept json
+// @produce json
+// @param payload body PayloadRound true "Number and decimals to keep"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APISuccess
+// @failure 400 {object} APIError
+// @router /round [post]
+func (h *Handler) roundHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadRound
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := scientific.Round(payload.Number, payload.Digits)
+	h.writeScientific(w, r, repository.TypeRound, result, err, payload.Number, float64(payload.Digits))
+}
+
+// Apply a function to a number
+//
+// @summary Apply a function to a number
+// @description Compute the square root, exponential, natural or decimal logarithm, absolute value, floor or ceiling of a number. The square root of a negative number and the logarithm of a non-positive one are rejected.
+// @tags Scientific
+// @accept json
+// @produce json
+// @param payload body PayloadNumber true "Number to apply the function to"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APISuccess
+// @failure 400 {object} APIError
+// @router /sqrt [post]
+// @router /exp [post]
+// @router /ln [post]
+// @router /log10 [post]
+// @router /abs [post]
+// @router /floor [post]
+// @router /ceil [post]
+func (h *Handler) numberHandler(opType repository.OperationType, fn func(float64) (float64, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload PayloadNumber
+		if err := decodeJSON(w, r, &payload); err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		result, err := fn(payload.Number)
+		h.writeScientific(w, r, opType, result, err, payload.Number)
+	}
+}
+
+// Apply a trigonometric function
+//
+// @summary Apply a trigonometric function
+// @description Compute the sine, cosine or tangent of an angle, or the arc sine, arc cosine or arc tangent of a number as an angle, in radians or degrees. The arc sine and arc cosine are only defined between -1 and 1, the tangent of an odd multiple of 90 degrees is rejected.
+// @tags Scientific
+// @accept json
+// @produce json
+// @param payload body PayloadAngle true "Number and unit of the angle"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APISuccess
+// @failure 400 {object} APIError
+// @router /sin [post]
+// @router /cos [post]
+// @router /tan [post]
+// @router /asin [post]
+// @router /acos [post]
+// @router /atan [post]
+func (h *Handler) angleHandler(opType repository.OperationType, fn func(float64, scientific.Unit) (float64, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload PayloadAngle
+		if err := decodeJSON(w, r, &payload); err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		unit, err := scientific.ParseUnit(string(payload.Unit))
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		result, err := fn(payload.Number, unit)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		// The unit is kept in the expression, the inputs are numbers only
+		expr := fmt.Sprintf("%s(%s, %s)", opType, formatFloat(payload.Number), unit)
+		h.recordScientific(w, r, opType, result, expr, payload.Number)
+	}
+}
+
+// writeScientific answers with the result of a scientific function, or with
+// its domain error, and records the operation.
+func (h *Handler) writeScientific(w http.ResponseWriter, r *http.Request, opType repository.OperationType, result float64, err error, inputs ...float64) {
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	expr := fmt.Sprintf("%s(%s)", opType, strings.Join(formatFloats(inputs...), ", "))
+	h.recordScientific(w, r, opType, result, expr, inputs...)
+}
+
+func (h *Handler) recordScientific(w http.ResponseWriter, r *http.Request, opType repository.OperationType, result float64, expr string, inputs ...float64) {
+	userID := r.Context().Value(userIDKey).(int)
+
+	param := repository.AddOperationParams{
+		Inputs:     formatFloats(inputs...),
+		Type:       opType,
+		Result:     formatFloat(result),
+		UserId:     userID,
+		Expression: expr,
+		RequestId:  requestID(r),
+	}
+
+	if err := h.repo.AddOperation(r.Context(), param); err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeSuccess(w, r, http.StatusOK, result)
+}
+
+// Describe numbers
+//
+// @summary Describe numbers
+// @description Compute the count, sum, minimum, maximum, mean, median, mode, variance and standard deviation of an array of numbers, and the requested percentiles. The sum is compensated and the variance computed with Welford's algorithm.
+// @tags Math
+// @accept json
+// @produce json
+// @param payload body PayloadStats true "Numbers to describe and percentiles to compute"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APIStatsSuccess
+// @failure 400 {object} APIError
+// @router /stats [post]
+func (h *Handler) statsHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadStats
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	summary, err := stats.Summarize(payload.Numbers, payload.Percentiles...)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	// The whole summary is the result of the operation
+	result, err := json.Marshal(summary)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	userID := r.Context().Value(userIDKey).(int)
+
+	param := repository.AddOperationParams{
+		Inputs:    formatFloats(payload.Numbers...),
+		Type:      repository.TypeStats,
+		Result:    string(result),
+		UserId:    userID,
+		RequestId: requestID(r),
+	}
+
+	if err := h.repo.AddOperation(r.Context(), param); err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeResult(w, r, http.StatusOK, APIStatsSuccess{*summary})
+}
+
+// Dot product
+//
+// @summary Dot product
+// @description Compute the dot product of two vectors of the same length
+// @tags Linear algebra
+// @accept json
+// @produce json
+// @param payload body PayloadVectors true "Vectors a and b"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APISuccess
+// @failure 400 {object} APIError
+// @router /vectors/dot [post]
+func (h *Handler) dotHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadVectors
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	h.linalgOperation(w, r, repository.TypeVectorDot, func() (any, error) {
+		return linalg.Dot(payload.A, payload.B)
+	}, payload.A, payload.B)
+}
+
+// Cross product
+//
+// @summary Cross product
+// @description Compute the cross product of two 3-dimensional vectors
+// @tags Linear algebra
+// @accept json
+// @produce json
+// @param payload body PayloadVectors true "Vectors a and b"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APIVectorSuccess
+// @failure 400 {object} APIError
+// @router /vectors/cross [post]
+func (h *Handler) crossHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadVectors
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	h.linalgOperation(w, r, repository.TypeVectorCross, func() (any, error) {
+		return linalg.Cross(payload.A, payload.B)
+	}, payload.A, payload.B)
+}
+
+// Norm of a vector
+//
+// @summary Norm of a vector
+// @description Compute the Euclidean norm of a vector
+// @tags Linear algebra
+// @accept json
+// @produce json
+// @param payload body PayloadVector true "Vector"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APISuccess
+// @failure 400 {object} APIError
+// @router /vectors/norm [post]
+func (h *Handler) normHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadVector
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	h.linalgOperation(w, r, repository.TypeVectorNorm, func() (any, error) {
+		return linalg.Norm(payload.Vector)
+	}, payload.Vector)
+}
+
+// Add or multiply two matrices
+//
+// @summary Add or multiply two matrices
+// @description Add two matrices of the same dimensions, or multiply a by b, b having as many rows as a has columns
+// @tags Linear algebra
+// @accept json
+// @produce json
+// @param payload body PayloadMatrices true "Matrices a and b, as arrays of rows"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APIMatrixSuccess
+// @failure 400 {object} APIError
+// @router /matrices/add [post]
+// @router /matrices/multiply [post]
+func (h *Handler) matricesHandler(opType repository.OperationType, fn func(a, b linalg.Matrix) (linalg.Matrix, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload PayloadMatrices
+		if err := decodeJSON(w, r, &payload); err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		h.linalgOperation(w, r, opType, func() (any, error) {
+			return fn(payload.A, payload.B)
+		}, payload.A, payload.B)
+	}
+}
+
+// Transpose or invert a matrix
+//
+// @summary Transpose or invert a matrix
+// @description Transpose a matrix, or invert a square matrix. Singular matrices cannot be inverted.
+// @tags Linear algebra
+// @accept json
+// @produce json
+// @param payload body PayloadMatrix true "Matrix, as an array of rows"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APIMatrixSuccess
+// @failure 400 {object} APIError
+// @router /matrices/transpose [post]
+// @router /matrices/inverse [post]
+func (h *Handler) matrixHandler(opType repository.OperationType, fn func(linalg.Matrix) (linalg.Matrix, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload PayloadMatrix
+		if err := decodeJSON(w, r, &payload); err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		h.linalgOperation(w, r, opType, func() (any, error) {
+			return fn(payload.Matrix)
+		}, payload.Matrix)
+	}
+}
+
+// Determinant of a matrix
+//
+// @summary Determinant of a matrix
+// @description Compute the determinant of a square matrix, 0 for a singular one
+// @tags Linear algebra
+// @accept json
+// @produce json
+// @param payload body PayloadMatrix true "Matrix, as an array of rows"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APISuccess
+// @failure 400 {object} APIError
+// @router /matrices/determinant [post]
+func (h *Handler) determinantHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadMatrix
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	h.linalgOperation(w, r, repository.TypeMatrixDeterminant, func() (any, error) {
+		return linalg.Determinant(payload.Matrix)
+	}, payload.Matrix)
+}
+
+// Solve a linear system
+//
+// @summary Solve a linear system
+// @description Solve Ax = b for a square non-singular matrix A
+// @tags Linear algebra
+// @accept json
+// @produce json
+// @param payload body PayloadSolve true "Matrix a, as an array of rows, and vector b"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APIVectorSuccess
+// @failure 400 {object} APIError
+// @router /matrices/solve [post]
+func (h *Handler) solveHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadSolve
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	h.linalgOperation(w, r, repository.TypeMatrixSolve, func() (any, error) {
+		return linalg.Solve(payload.A, payload.B)
+	}, payload.A, payload.B)
+}
+
+// linalgOperation rejects the operands larger than conf.Math.MaxMatrixSize,
+// then computes the operation and records it with its operands and result as
+// JSON text.
+func (h *Handler) linalgOperation(w http.ResponseWriter, r *http.Request, opType repository.OperationType, compute func() (any, error), operands ...any) {
+	inputs := make([]string, len(operands))
+	for i, operand := range operands {
+		var err error
+		switch operand := operand.(type) {
+		case linalg.Vector:
+			err = operand.CheckSize(conf.Math.MaxMatrixSize)
+		case linalg.Matrix:
+			err = operand.CheckSize(conf.Math.MaxMatrixSize)
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		text, err := json.Marshal(operand)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		inputs[i] = string(text)
+	}
+
+	result, err := compute()
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var text string
+	if number, ok := result.(float64); ok {
+		text = formatFloat(number)
+	} else {
+		b, err := json.Marshal(result)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		text = string(b)
+	}
+
+	userID := r.Context().Value(userIDKey).(int)
+
+	param := repository.AddOperationParams{
+		Inputs:    inputs,
+		Type:      opType,
+		Result:    text,
+		UserId:    userID,
+		RequestId: requestID(r),
+	}
+
+	if err := h.repo.AddOperation(r.Context(), param); err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	switch result := result.(type) {
+	case linalg.Vector:
+		writeResult(w, r, http.StatusOK, APIVectorSuccess{result})
+	case linalg.Matrix:
+		writeResult(w, r, http.StatusOK, APIMatrixSuccess{result})
+	default:
+		writeSuccess(w, r, http.StatusOK, result.(float64))
+	}
+}
+
+// Complex arithmetic
+//
+// @summary Complex arithmetic
+// @description Add, substract, multiply or divide two complex numbers
+// @tags Complex numbers
+// @accept json
+// @produce json
+// @param payload body PayloadComplexPair true "Complex numbers, as {re, im} objects or a+bi strings"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APIComplexSuccess
+// @failure 400 {object} APIError
+// @router /complex/add [post]
+// @router /complex/substract [post]
+// @router /complex/multiply [post]
+// @router /complex/divide [post]
+func (h *Handler) complexPairHandler(opType repository.OperationType, fn func(a, b complex128) (complex128, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload PayloadComplexPair
+		if err := decodeJSON(w, r, &payload); err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		result, err := fn(payload.Number1.Complex(), payload.Number2.Complex())
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		h.recordComplex(w, r, opType, result, "", payload.Number1.String(), payload.Number2.String())
+	}
+}
+
+// Magnitude of a complex number
+//
+// @summary Magnitude of a complex number
+// @description Compute the modulus of a complex number
+// @tags Complex numbers
+// @accept json
+// @produce json
+// @param payload body PayloadComplex true "Complex number, as a {re, im} object or an a+bi string"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APISuccess
+// @failure 400 {object} APIError
+// @router /complex/magnitude [post]
+func (h *Handler) complexMagnitudeHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadComplex
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := complexmath.Magnitude(payload.Number.Complex())
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	h.recordComplex(w, r, repository.TypeComplexMagnitude, result, "", payload.Number.String())
+}
+
+// Phase of a complex number
+//
+// @summary Phase of a complex number
+// @description Compute the phase of a complex number, between -π and π radians or -180 and 180 degrees
+// @tags Complex numbers
+// @accept json
+// @produce json
+// @param payload body PayloadComplexAngle true "Complex number and unit of the phase"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APISuccess
+// @failure 400 {object} APIError
+// @router /complex/phase [post]
+func (h *Handler) complexPhaseHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadComplexAngle
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	unit, err := scientific.ParseUnit(string(payload.Unit))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	result := complexmath.Phase(payload.Number.Complex(), unit)
+
+	// The unit is kept in the expression, the inputs are numbers only
+	expr := fmt.Sprintf("%s(%s, %s)", repository.TypeComplexPhase, payload.Number, unit)
+	h.recordComplex(w, r, repository.TypeComplexPhase, result, expr, payload.Number.String())
+}
+
+// Conjugate of a complex number
+//
+// @summary Conjugate of a complex number
+// @description Compute the conjugate of a complex number, a-bi for a+bi
+// @tags Complex numbers
+// @accept json
+// @produce json
+// @param payload body PayloadComplex true "Complex number, as a {re, im} object or an a+bi string"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APIComplexSuccess
+// @failure 400 {object} APIError
+// @router /complex/conjugate [post]
+func (h *Handler) complexConjugateHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadComplex
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	result := complexmath.Conjugate(payload.Number.Complex())
+
+	h.recordComplex(w, r, repository.TypeComplexConjugate, result, "", payload.Number.String())
+}
+
+// Polar form of a complex number
+//
+// @summary Polar form of a complex number
+// @description Convert a complex number to its modulus and phase
+// @tags Complex numbers
+// @accept json
+// @produce json
+// @param payload body PayloadComplexAngle true "Complex number and unit of the phase"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APIPolarSuccess
+// @failure 400 {object} APIError
+// @router /complex/polar [post]
+func (h *Handler) complexPolarHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadComplexAngle
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	unit, err := scientific.ParseUnit(string(payload.Unit))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := complexmath.ToPolar(payload.Number.Complex(), unit)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	expr := fmt.Sprintf("%s(%s, %s)", repository.TypeComplexPolar, payload.Number, unit)
+	h.recordComplex(w, r, repository.TypeComplexPolar, result, expr, payload.Number.String())
+}
+
+// Rectangular form of a complex number
+//
+// @summary Rectangular form of a complex number
+// @description Convert a modulus and a phase to a complex number. In degrees, the multiples of 90 are exact.
+// @tags Complex numbers
+// @accept json
+// @produce json
+// @param payload body PayloadPolar true "Modulus, phase and unit of the phase"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APIComplexSuccess
+// @failure 400 {object} APIError
+// @router /complex/rectangular [post]
+func (h *Handler) complexRectangularHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadPolar
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	unit, err := scientific.ParseUnit(string(payload.Unit))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := complexmath.FromPolar(complexmath.Polar{Modulus: payload.Modulus, Phase: payload.Phase}, unit)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	expr := fmt.Sprintf("%s(%s, %s, %s)", repository.TypeComplexRectangular, formatFloat(payload.Modulus), formatFloat(payload.Phase), unit)
+	h.recordComplex(w, r, repository.TypeComplexRectangular, result, expr, formatFloats(payload.Modulus, payload.Phase)...)
+}
+
+// Complex power
+//
+// @summary Complex power
+// @description Raise a complex number to a complex power, on the principal branch. Integer powers are exact, e.g. i² is -1.
+// @tags Complex numbers
+// @accept json
+// @produce json
+// @param payload body PayloadComplexPow true "Base and exponent, as {re, im} objects or a+bi strings"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APIComplexSuccess
+// @failure 400 {object} APIError
+// @router /complex/pow [post]
+func (h *Handler) complexPowHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadComplexPow
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := complexmath.Pow(payload.Base.Complex(), payload.Exponent.Complex())
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	h.recordComplex(w, r, repository.TypeComplexPow, result, "", payload.Base.String(), payload.Exponent.String())
+}
+
+// Complex root
+//
+// @summary Complex root
+// @description Compute the principal root of any degree of a complex number, e.g. the square root of -4 is 2i
+// @tags Complex numbers
+// @accept json
+// @produce json
+// @param payload body PayloadComplexRoot true "Complex number and degree of the root"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APIComplexSuccess
+// @failure 400 {object} APIError
+// @router /complex/root [post]
+func (h *Handler) complexRootHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PayloadComplexRoot
+	if err := decodeJSON(w, r, &payload); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := complexmath.Root(payload.Number.Complex(), payload.Degree)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	h.recordComplex(w, r, repository.TypeComplexRoot, result, "", payload.Number.String(), strconv.Itoa(payload.Degree))
+}
+
+// recordComplex records a complex operation and answers with its result: a
+// complex number, returned both by its parts and in the a+bi form, a real
+// number or a polar form, stored as JSON text.
+func (h *Handler) recordComplex(w http.ResponseWriter, r *http.Request, opType repository.OperationType, result any, expr string, inputs ...string) {
+	var (
+		text     string
+		response any
+	)
+	switch result := result.(type) {
+	case complex128:
+		text = complexmath.Format(result)
+		response = APIComplexSuccess{complexmath.NewNumber(result), text}
+	case complexmath.Polar:
+		b, err := json.Marshal(result)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		text = string(b)
+		response = APIPolarSuccess{result}
+	case float64:
+		text = formatFloat(result)
+		response = APISuccess{result}
+	}
+
+	userID := r.Context().Value(userIDKey).(int)
+
+	param := repository.AddOperationParams{
+		Inputs:     inputs,
+		Type:       opType,
+		Result:     text,
+		UserId:     userID,
+		Expression: expr,
+		RequestId:  requestID(r),
+	}
+
+	if err := h.repo.AddOperation(r.Context(), param); err != nil {
 		writeError(w, r, http.StatusInternalServerError, err)
 		return
 	}
 
-	writeSuccess(w, r, http.StatusOK, result)
+	writeResult(w, r, http.StatusOK, response)
+}
+
+// List operations
+//
+// @summary List operations
+// @description List the calculation history of the authenticated user
+// @tags History
+// @produce json
+// @param type query string false "Operation type" Enums(add, substract, multiply, divide, sum, evaluate, pow, sqrt, root, exp, ln, log10, log, sin, cos, tan, asin, acos, atan, abs, floor, ceil, round, stats, vector_dot, vector_cross, vector_norm, matrix_add, matrix_multiply, matrix_transpose, matrix_determinant, matrix_inverse, matrix_solve, complex_add, complex_substract, complex_multiply, complex_divide, complex_magnitude, complex_phase, complex_conjugate, complex_polar, complex_rectangular, complex_pow, complex_root)
+// @param request_id query string false "Only the operation recorded by this request"
+// @param from query string false "Only operations created at or after this RFC 3339 date"
+// @param to query string false "Only operations created at or before this RFC 3339 date"
+// @param min_result query number false "Minimum result, statistics, vectors, matrices and complex numbers are skipped"
+// @param max_result query number false "Maximum result, statistics, vectors, matrices and complex numbers are skipped"
+// @param order query string false "Sort order by creation" Enums(desc, asc)
+// @param limit query int false "Page size (1-100)"
+// @param cursor query string false "Cursor returned by the previous page"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} APIOperationsPage
+// @failure 400 {object} APIError
+// @router /operations [get]
+func (h *Handler) listOperationsHandler(w http.ResponseWriter, r *http.Request) {
+	params, err := parseListOperationsParams(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	params.UserId = r.Context().Value(userIDKey).(int)
+
+	// Fetch one extra row to know whether there is a next page
+	limit := params.Limit
+	params.Limit++
+
+	operations, err := h.repo.ListOperations(r.Context(), params)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	page := APIOperationsPage{Data: operations}
+	if len(operations) > limit {
+		page.Data = operations[:limit]
+		page.NextCursor = encodeCursor(page.Data[limit-1].Id)
+	}
+
+	writeResult(w, r, http.StatusOK, page)
+}
+
+// Get an operation
+//
+// @summary Get an operation
+// @description Get one operation of the authenticated user
+// @tags History
+// @produce json
+// @param id path int true "Operation ID"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @success 200 {object} repository.Operations
+// @failure 400 {object} APIError
+// @failure 404 {object} APIError
+// @router /operations/{id} [get]
+func (h *Handler) getOperationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("%w: id", ErrInvalidQuery))
+		return
+	}
+
+	userID := r.Context().Value(userIDKey).(int)
+
+	operation, err := h.repo.FindOperationById(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrOperationNotFound) {
+			writeError(w, r, http.StatusNotFound, err)
+			return
+		}
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeResult(w, r, http.StatusOK, operation)
+}
+
+func parseListOperationsParams(r *http.Request) (repository.ListOperationsParams, error) {
+	query := r.URL.Query()
+	params := repository.ListOperationsParams{
+		Type:      repository.OperationType(query.Get("type")),
+		RequestId: query.Get("request_id"),
+		Order:     repository.SortDesc,
+		Limit:     defaultPageSize,
+	}
+
+	if value := query.Get("from"); value != "" {
+		from, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return params, fmt.Errorf("%w: from should be an RFC 3339 date", ErrInvalidQuery)
+		}
+		params.From = from
+	}
+
+	if value := query.Get("to"); value != "" {
+		to, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return params, fmt.Errorf("%w: to should be an RFC 3339 date", ErrInvalidQuery)
+		}
+		params.To = to
+	}
+
+	var err error
+	if params.MinResult, err = parseFloatQuery(query, "min_result"); err != nil {
+		return params, err
+	}
+	if params.MaxResult, err = parseFloatQuery(query, "max_result"); err != nil {
+		return params, err
+	}
+
+	switch order := repository.SortOrder(strings.ToLower(query.Get("order"))); order {
+	case "":
+	case repository.SortAsc, repository.SortDesc:
+		params.Order = order
+	default:
+		return params, fmt.Errorf("%w: order should be asc or desc", ErrInvalidQuery)
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return params, fmt.Errorf("%w: limit should be between 1 and %d", ErrInvalidQuery, maxPageSize)
+		}
+		params.Limit = limit
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		afterID, err := decodeCursor(value)
+		if err != nil {
+			return params, fmt.Errorf("%w: cursor", ErrInvalidQuery)
+		}
+		params.AfterId = afterID
+	}
+
+	return params, nil
+}
+
+func parseFloatQuery(query url.Values, name string) (*float64, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s should be a number", ErrInvalidQuery, name)
+	}
+
+	return &number, nil
+}
+
+// Cursors are opaque to clients: they only wrap the id of the last operation
+// of a page
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || id < 1 {
+		return 0, ErrInvalidQuery
+	}
+
+	return id, nil
+}
+
+type calcOptions struct {
+	mode      repository.OperationMode
+	precision int
+	rounding  decimal.RoundingMode
+}
+
+func parseCalcOptions(r *http.Request) (calcOptions, error) {
+	opts := calcOptions{mode: repository.ModeFloat}
+
+	switch mode := repository.OperationMode(strings.ToLower(r.Header.Get(headerMode))); mode {
+	case "", repository.ModeFloat:
+		return opts, nil
+	case repository.ModeDecimal, repository.ModeFraction:
+		opts.mode = mode
+	default:
+		return opts, fmt.Errorf("%w: %q", ErrInvalidMode, mode)
+	}
+
+	if value := r.Header.Get(headerPrecision); value != "" {
+		precision, err := strconv.Atoi(value)
+		if err != nil {
+			return opts, decimal.ErrPrecisionRange
+		}
+		if err := decimal.ValidatePrecision(precision); err != nil {
+			return opts, err
+		}
+		opts.precision = precision
+	}
+
+	rounding, err := decimal.ParseRoundingMode(r.Header.Get(headerRounding))
+	if err != nil {
+		return opts, err
+	}
+	opts.rounding = rounding
+
+	return opts, nil
+}
+
+// decimalMode hands the request over to decimalHandler when the caller opted
+// into the decimal or fraction mode, and to the regular float64 handler
+// otherwise.
+func (h *Handler) decimalMode(op repository.OperationType) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			opts, err := parseCalcOptions(r)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, err)
+				return
+			}
+
+			if opts.mode == repository.ModeFloat {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h.decimalHandler(w, r, op, opts)
+		}
+	}
+}
+
+func (h *Handler) decimalHandler(w http.ResponseWriter, r *http.Request, op repository.OperationType, opts calcOptions) {
+	var inputs []DecimalString
+	if op == repository.TypeSum {
+		var payload PayloadSumDecimal
+		if err := decodeJSON(w, r, &payload); err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if len(payload) < 2 {
+			writeError(w, r, http.StatusBadRequest, ErrLengthSum)
+			return
+		}
+
+		inputs = payload
+	} else {
+		var payload PayloadDecimal
+		if err := decodeJSON(w, r, &payload); err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		inputs = []DecimalString{payload.Number1, payload.Number2}
+	}
+
+	texts := make([]string, len(inputs))
+	for i, input := range inputs {
+		texts[i] = string(input)
+	}
+
+	parse := decimal.ParseAll
+	if opts.mode == repository.ModeFraction {
+		parse = decimal.ParseFractions
+	}
+
+	values, err := parse(texts)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	var result *big.Rat
+	switch op {
+	case repository.TypeAdd:
+		result = decimal.Add(values[0], values[1])
+	case repository.TypeSum:
+		result = decimal.Sum(values)
+	case repository.TypeSubstract:
+		result = decimal.Sub(values[0], values[1])
+	case repository.TypeMultiply:
+		result = decimal.Mul(values[0], values[1])
+	case repository.TypeDivide:
+		if result, err = decimal.Quo(values[0], values[1]); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrDividyByZero)
+			return
+		}
+	default:
+		writeError(w, r, http.StatusBadRequest, fmt.Errorf("%w for %s", ErrInvalidMode, op))
+		return
+	}
+
+	formatted := decimal.Format(result, opts.precision, opts.rounding)
+
+	var response any = APIDecimalSuccess{formatted}
+	stored := formatted
+	if opts.mode == repository.ModeFraction {
+		// The history keeps the exact fractions, the decimal is only returned
+		for i, value := range values {
+			texts[i] = decimal.FormatFraction(value)
+		}
+		stored = decimal.FormatFraction(result)
+		response = APIFractionSuccess{Result: stored, Decimal: formatted}
+	} else {
+		// Inputs always have a finite decimal expansion, so they are stored exactly
+		for i, value := range values {
+			texts[i] = decimal.Format(value, 0, decimal.HalfEven)
+		}
+	}
+
+	userID := r.Context().Value(userIDKey).(int)
+
+	param := repository.AddOperationParams{
+		Inputs:    texts,
+		Type:      op,
+		Result:    stored,
+		Mode:      opts.mode,
+		UserId:    userID,
+		RequestId: requestID(r),
+	}
+
+	if err := h.repo.AddOperation(r.Context(), param); err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeResult(w, r, http.StatusOK, response)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func formatFloats(xs ...float64) []string {
+	texts := make([]string, len(xs))
+	for i, x := range xs {
+		texts[i] = formatFloat(x)
+	}
+
+	return texts
 }
 
-func decodeJSON(r *http.Request, payload any) error {
+// decodeJSON decodes the body of r into payload. Bodies larger than
+// max_body_size are not read further, writeError answers them with a 413.
+func decodeJSON(w http.ResponseWriter, r *http.Request, payload any) error {
 	if r.ContentLength == 0 {
 		return ErrMissingBody
 	}
 
+	r.Body = http.MaxBytesReader(w, r.Body, conf.MaxBodySize)
+
 	return json.NewDecoder(r.Body).Decode(payload)
 }
 
+// encodeJSON encodes payload before writing the status, so that nothing is
+// written when it cannot be encoded, e.g. for an infinite number.
 func encodeJSON(w http.ResponseWriter, statusCode int, payload any) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 
-	return json.NewEncoder(w).Encode(payload)
+	_, err = w.Write(append(b, '\n'))
+	return err
 }
 
 func writeSuccess(w http.ResponseWriter, r *http.Request, statusCode int, payload float64) error {
-	reqID := r.Context().Value(requestIDKey).(string)
+	return writeResult(w, r, statusCode, APISuccess{payload})
+}
+
+func writeResult(w http.ResponseWriter, r *http.Request, statusCode int, payload any) error {
+	reqID := requestID(r)
 
-	logger.Info("Request successful",
+	logger.InfoContext(r.Context(), "Request successful",
 		slog.Int("statusCode", statusCode),
 		slog.String("remoteAddr", r.RemoteAddr),
 		slog.Group("request",
@@ -376,13 +2436,31 @@ func writeSuccess(w http.ResponseWriter, r *http.Request, statusCode int, payloa
 		),
 	)
 
-	return encodeJSON(w, statusCode, APISuccess{payload})
+	err := encodeJSON(w, statusCode, payload)
+
+	var unsupported *json.UnsupportedValueError
+	if errors.As(err, &unsupported) {
+		return writeError(w, r, http.StatusInternalServerError, err)
+	}
+
+	return err
 }
 
 func writeError(w http.ResponseWriter, r *http.Request, statusCode int, err error) error {
-	reqID := r.Context().Value(requestIDKey).(string)
+	reqID := requestID(r)
+
+	cause := err
+	if statusCode == http.StatusInternalServerError {
+		statusCode, err = unavailableError(w, err)
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		statusCode = http.StatusRequestEntityTooLarge
+		err = fmt.Errorf("%w: at most %d bytes", ErrBodyTooLarge, tooLarge.Limit)
+	}
 
-	logger.Error(err.Error(),
+	logger.ErrorContext(r.Context(), cause.Error(),
 		slog.Int("statusCode", statusCode),
 		slog.String("remoteAddr", r.RemoteAddr),
 		slog.Group("request",
@@ -392,5 +2470,57 @@ func writeError(w http.ResponseWriter, r *http.Request, statusCode int, err erro
 		),
 	)
 
-	return encodeJSON(w, statusCode, APIError{err.Error()})
+	if statusCode >= http.StatusInternalServerError {
+		spanError(r.Context(), cause)
+	}
+
+	apiErr := APIError{Error: err.Error(), RequestID: reqID}
+
+	var (
+		parseErr  *expression.ParseError
+		scopeErr  *MissingScopeError
+		domainErr *scientific.DomainError
+		linalgErr *linalg.Error
+		cmplxErr  *complexmath.Error
+	)
+	switch {
+	case errors.Is(err, ErrNotFinite), errors.Is(err, expression.ErrNotFinite):
+		apiErr.Code = scientific.CodeNotFinite
+	case errors.As(err, &parseErr):
+		apiErr.Details = parseErr
+	case errors.As(err, &scopeErr):
+		apiErr.Details = scopeErr
+	case errors.As(err, &domainErr):
+		apiErr.Code = domainErr.Code
+		apiErr.Details = domainErr
+	case errors.As(err, &linalgErr):
+		apiErr.Code = linalgErr.Code
+		if linalgErr.Operation != "" {
+			apiErr.Details = linalgErr
+		}
+	case errors.As(err, &cmplxErr):
+		apiErr.Code = cmplxErr.Code
+		if cmplxErr.Operation != "" {
+			apiErr.Details = cmplxErr
+		}
+	}
+
+	return encodeJSON(w, statusCode, apiErr)
+}
+
+// unavailableError tells apart, among the internal errors, the database
+// calls that timed out, were canceled by the client or found the database
+// locked, so that they are answered with 503 or 504 rather than 500.
+func unavailableError(w http.ResponseWriter, err error) (int, error) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, ErrDatabaseTimeout
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable, ErrRequestCanceled
+	case isDatabaseBusy(err):
+		w.Header().Set("Retry-After", "1")
+		return http.StatusServiceUnavailable, ErrDatabaseBusy
+	default:
+		return http.StatusInternalServerError, err
+	}
 }
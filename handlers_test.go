@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestDecodeJSONTooLarge checks that a body above max_body_size is rejected
+// before it is decoded, rather than after the matrix size check.
+func TestDecodeJSONTooLarge(t *testing.T) {
+	limit := conf.MaxBodySize
+	conf.MaxBodySize = 1024
+	t.Cleanup(func() { conf.MaxBodySize = limit })
+
+	rows := strings.Repeat("[1, 2, 3],", 200)
+	body := `{"matrix": [` + rows + `[1, 2, 3]]}`
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/matrices/transpose", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	var payload PayloadMatrix
+	err := decodeJSON(w, r, &payload)
+
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("got error %v, expected an *http.MaxBytesError", err)
+	}
+
+	writeError(w, r, http.StatusBadRequest, err)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("got status %d, expected %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+// TestWriteSuccessNotFinite checks that a result JSON cannot encode is
+// answered with an error rather than an empty 200.
+func TestWriteSuccessNotFinite(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/add", nil)
+	w := httptest.NewRecorder()
+
+	writeSuccess(w, r, http.StatusOK, math.Inf(1))
+
+	var apiErr APIError
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d, expected %d", w.Code, http.StatusInternalServerError)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &apiErr); err != nil || apiErr.Error == "" {
+		t.Errorf("got body %q, expected an error", w.Body)
+	}
+}
+
+func TestWriteErrorNotFinite(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/add", nil)
+	w := httptest.NewRecorder()
+
+	writeError(w, r, http.StatusBadRequest, ErrNotFinite)
+
+	var apiErr APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &apiErr); err != nil || w.Code != http.StatusBadRequest || apiErr.Code != "not_finite" {
+		t.Errorf("got status %d and body %q, expected a 400 with the not_finite code", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/NDOY3M4N/api-calculator/ratelimit"
+	"github.com/NDOY3M4N/api-calculator/repository"
+)
+
+const readinessTimeout = 2 * time.Second
+
+const (
+	statusOK   = "ok"
+	statusFail = "fail"
+)
+
+// buildTime is set at build time with -ldflags "-X main.buildTime=...". The
+// commit time is reported when it is not.
+var buildTime string
+
+type APICheck struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type APIHealth struct {
+	Status string              `json:"status"`
+	Checks map[string]APICheck `json:"checks,omitempty"`
+}
+
+type APIVersion struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Health serves the probes of the orchestrator. They are mounted outside of
+// the middleware stack, so they need neither credentials nor tokens.
+type Health struct {
+	repo    repository.Store
+	limiter *ratelimit.KeyedLimiter
+	// schemaVersion is the version the database should be at
+	schemaVersion int64
+
+	draining atomic.Bool
+}
+
+func NewHealth(repo repository.Store, limiter *ratelimit.KeyedLimiter, schemaVersion int64) *Health {
+	return &Health{repo: repo, limiter: limiter, schemaVersion: schemaVersion}
+}
+
+// SetDraining makes /readyz fail from now on, as the server is shutting down.
+func (hc *Health) SetDraining() {
+	hc.draining.Store(true)
+}
+
+func (hc *Health) RegisterRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /healthz", hc.healthzHandler)
+	router.HandleFunc("GET /readyz", hc.readyzHandler)
+	router.HandleFunc("GET /version", versionHandler)
+}
+
+// healthzHandler answers as long as the process can serve requests.
+func (hc *Health) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	encodeJSON(w, http.StatusOK, APIHealth{Status: statusOK})
+}
+
+// readyzHandler tells whether the instance can take traffic, with the
+// outcome of each check. A failed check only gets a fixed message, the
+// underlying error is logged.
+func (hc *Health) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	health := APIHealth{
+		Status: statusOK,
+		Checks: map[string]APICheck{
+			"database":    check(r.Context(), "database", "database is unreachable", hc.repo.Ping(ctx)),
+			"migrations":  check(r.Context(), "migrations", "database schema is not up to date", hc.checkMigrations(ctx)),
+			"ratelimiter": check(r.Context(), "ratelimiter", "rate limiter is not running", hc.checkLimiter()),
+			"shutdown":    check(r.Context(), "shutdown", "server is shutting down", hc.checkDraining()),
+		},
+	}
+
+	statusCode := http.StatusOK
+	for _, c := range health.Checks {
+		if c.Status != statusOK {
+			health.Status = statusFail
+			statusCode = http.StatusServiceUnavailable
+		}
+	}
+
+	encodeJSON(w, statusCode, health)
+}
+
+func (hc *Health) checkMigrations(ctx context.Context) error {
+	version, err := hc.repo.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if version != hc.schemaVersion {
+		return fmt.Errorf("database schema is at version %d, expected %d", version, hc.schemaVersion)
+	}
+
+	return nil
+}
+
+func (hc *Health) checkLimiter() error {
+	if !hc.limiter.Running() {
+		return fmt.Errorf("rate limiter is not running")
+	}
+
+	return nil
+}
+
+func (hc *Health) checkDraining() error {
+	if hc.draining.Load() {
+		return fmt.Errorf("server is shutting down")
+	}
+
+	return nil
+}
+
+// check returns the outcome of the check called name, with message when err
+// is set. err is logged rather than returned, it may reveal the internals of
+// the database to the unauthenticated callers of the probe.
+func check(ctx context.Context, name, message string, err error) APICheck {
+	if err != nil {
+		logger.WarnContext(ctx, "Readiness check failed",
+			slog.String("check", name),
+			slog.String("message", err.Error()),
+		)
+		return APICheck{Status: statusFail, Error: message}
+	}
+
+	return APICheck{Status: statusOK}
+}
+
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	version := APIVersion{Version: "unknown", BuildTime: buildTime}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		version.Version = info.Main.Version
+		version.GoVersion = info.GoVersion
+
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				version.Revision = setting.Value
+			case "vcs.modified":
+				version.Modified = setting.Value == "true"
+			case "vcs.time":
+				if version.BuildTime == "" {
+					version.BuildTime = setting.Value
+				}
+			}
+		}
+	}
+
+	encodeJSON(w, http.StatusOK, version)
+}
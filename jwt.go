@@ -1,30 +1,117 @@
 package main
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/NDOY3M4N/api-calculator/repository"
 )
 
-func GenerateToken(userID int) (string, error) {
-	expiration := time.Duration(3600 * time.Second)
+const tokenAudience = "api-calculator"
 
-	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userID":    strconv.Itoa(userID),
-		"expiredAt": time.Now().Add(expiration).Unix(),
-	})
+var ErrInvalidSubject = errors.New("token subject is not a user ID")
+
+// Claims holds the registered claims, the user ID being the subject, and the
+// role of the user when the token was issued.
+type Claims struct {
+	Role repository.Role `json:"role"`
+	jwt.RegisteredClaims
+}
+
+func (c *Claims) UserID() (int, error) {
+	userID, err := strconv.Atoi(c.Subject)
+	if err != nil || userID < 1 {
+		return 0, ErrInvalidSubject
+	}
 
-	return claims.SignedString([]byte(envs.JWTSecret))
+	return userID, nil
 }
 
-func ValidateToken(tokenString string) (*jwt.Token, error) {
-	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method, %v", token.Header["alg"])
-		}
+func GenerateToken(userID int, role repository.Role) (string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
 
-		return []byte(envs.JWTSecret), nil
+	now := time.Now()
+	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(userID),
+			Issuer:    conf.JWT.Issuer,
+			Audience:  jwt.ClaimStrings{tokenAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(conf.JWT.AccessTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ID:        jti,
+		},
 	})
+
+	return claims.SignedString([]byte(conf.JWT.Secret))
+}
+
+// ValidateToken checks the signature and the registered claims of
+// tokenString: it should be issued by and for this API, not be expired and
+// not be used before its nbf date.
+func ValidateToken(tokenString string) (*Claims, error) {
+	claims := new(Claims)
+
+	_, err := jwt.ParseWithClaims(
+		tokenString,
+		claims,
+		func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method, %v", token.Header["alg"])
+			}
+
+			return []byte(conf.JWT.Secret), nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(conf.JWT.Issuer),
+		jwt.WithAudience(tokenAudience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.ID == "" {
+		return nil, fmt.Errorf("token has no jti claim")
+	}
+
+	return claims, nil
+}
+
+// GenerateRefreshToken returns an opaque refresh token and the hash under
+// which it should be stored.
+func GenerateRefreshToken() (token, hash string, err error) {
+	token, err = randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, HashRefreshToken(token), nil
+}
+
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating random token: %s", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
 }
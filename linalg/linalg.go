@@ -0,0 +1,414 @@
+// Package linalg implements the vector and matrix operations of the
+// calculator on float64: dot and cross products, norms, sums and products of
+// matrices, transposition, determinants, inverses and linear systems.
+//
+// The operands are checked before any computation and the invalid ones are
+// reported as an *Error, whose Code tells clients why without parsing the
+// message. Determinants, inverses and systems are computed from an LU
+// decomposition with partial pivoting.
+package linalg
+
+import (
+	"fmt"
+	"math"
+)
+
+// Codes of the errors.
+const (
+	// CodeEmpty is a vector or a matrix without any number
+	CodeEmpty = "empty"
+	// CodeRaggedMatrix is a matrix whose rows are not of the same length
+	CodeRaggedMatrix = "ragged_matrix"
+	// CodeDimensionMismatch is a pair of operands whose dimensions do not
+	// fit the operation, e.g. the product of a 2x3 and a 2x3 matrix
+	CodeDimensionMismatch = "dimension_mismatch"
+	// CodeNotSquare is the determinant, inverse or system of a matrix that is
+	// not square
+	CodeNotSquare = "not_square"
+	// CodeNot3D is the cross product of vectors that are not 3-dimensional
+	CodeNot3D = "not_3d"
+	// CodeSingular is the inverse or system of a singular matrix
+	CodeSingular = "singular_matrix"
+	// CodeTooLarge is an operand larger than the maximum size of the server
+	CodeTooLarge = "too_large"
+	// CodeNotFinite is a result too large for float64
+	CodeNotFinite = "not_finite"
+)
+
+// Error is returned when the operands of an operation are invalid.
+type Error struct {
+	Code      string `json:"-"`
+	Operation string `json:"operation,omitempty"`
+	Reason    string `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Operation == "" {
+		return e.Reason
+	}
+
+	return fmt.Sprintf("%s: %s", e.Operation, e.Reason)
+}
+
+func errorf(code, operation, format string, args ...any) error {
+	return &Error{Code: code, Operation: operation, Reason: fmt.Sprintf(format, args...)}
+}
+
+type Vector []float64
+
+// Matrix is a slice of rows.
+type Matrix [][]float64
+
+// CheckSize rejects a vector of more than max numbers.
+func (v Vector) CheckSize(max int) error {
+	if len(v) > max {
+		return errorf(CodeTooLarge, "", "vectors are limited to %d numbers", max)
+	}
+
+	return nil
+}
+
+// CheckSize rejects a matrix of more than max rows or columns.
+func (m Matrix) CheckSize(max int) error {
+	if len(m) > max {
+		return errorf(CodeTooLarge, "", "matrices are limited to %d rows and columns", max)
+	}
+	for _, row := range m {
+		if len(row) > max {
+			return errorf(CodeTooLarge, "", "matrices are limited to %d rows and columns", max)
+		}
+	}
+
+	return nil
+}
+
+// dims returns the number of rows and columns of m, which should not be
+// empty nor ragged.
+func (m Matrix) dims(operation string) (int, int, error) {
+	if len(m) == 0 || len(m[0]) == 0 {
+		return 0, 0, errorf(CodeEmpty, operation, "a matrix should have at least one number")
+	}
+	for i, row := range m {
+		if len(row) != len(m[0]) {
+			return 0, 0, errorf(CodeRaggedMatrix, operation, "row %d has %d columns, expected %d", i+1, len(row), len(m[0]))
+		}
+	}
+
+	return len(m), len(m[0]), nil
+}
+
+// square returns the size of m, which should be square.
+func (m Matrix) square(operation string) (int, error) {
+	rows, cols, err := m.dims(operation)
+	if err != nil {
+		return 0, err
+	}
+	if rows != cols {
+		return 0, errorf(CodeNotSquare, operation, "a %dx%d matrix is not square", rows, cols)
+	}
+
+	return rows, nil
+}
+
+func newMatrix(rows, cols int) Matrix {
+	m := make(Matrix, rows)
+	for i := range m {
+		m[i] = make([]float64, cols)
+	}
+
+	return m
+}
+
+func finite(operation string, xs ...float64) error {
+	for _, x := range xs {
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			return errorf(CodeNotFinite, operation, "result is not a finite number")
+		}
+	}
+
+	return nil
+}
+
+func (m Matrix) finite(operation string) error {
+	for _, row := range m {
+		if err := finite(operation, row...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pair checks that a and b are non-empty vectors of the same length.
+func pair(operation string, a, b Vector) error {
+	if len(a) == 0 || len(b) == 0 {
+		return errorf(CodeEmpty, operation, "a vector should have at least one number")
+	}
+	if len(a) != len(b) {
+		return errorf(CodeDimensionMismatch, operation, "vectors of %d and %d numbers", len(a), len(b))
+	}
+
+	return nil
+}
+
+// Dot returns the dot product of a and b.
+func Dot(a, b Vector) (float64, error) {
+	if err := pair("dot", a, b); err != nil {
+		return 0, err
+	}
+
+	var result float64
+	for i := range a {
+		result += a[i] * b[i]
+	}
+
+	return result, finite("dot", result)
+}
+
+// Cross returns the cross product of the 3-dimensional vectors a and b.
+func Cross(a, b Vector) (Vector, error) {
+	if err := pair("cross", a, b); err != nil {
+		return nil, err
+	}
+	if len(a) != 3 {
+		return nil, errorf(CodeNot3D, "cross", "the cross product is only defined for 3-dimensional vectors")
+	}
+
+	result := Vector{
+		a[1]*b[2] - a[2]*b[1],
+		a[2]*b[0] - a[0]*b[2],
+		a[0]*b[1] - a[1]*b[0],
+	}
+
+	return result, finite("cross", result...)
+}
+
+// Norm returns the Euclidean norm of v. It is accumulated with math.Hypot so
+// that the squares of large numbers do not overflow.
+func Norm(v Vector) (float64, error) {
+	if len(v) == 0 {
+		return 0, errorf(CodeEmpty, "norm", "a vector should have at least one number")
+	}
+
+	var result float64
+	for _, x := range v {
+		result = math.Hypot(result, x)
+	}
+
+	return result, finite("norm", result)
+}
+
+// Add returns the sum of a and b, which should have the same dimensions.
+func Add(a, b Matrix) (Matrix, error) {
+	rows, cols, err := a.dims("add")
+	if err != nil {
+		return nil, err
+	}
+	bRows, bCols, err := b.dims("add")
+	if err != nil {
+		return nil, err
+	}
+	if rows != bRows || cols != bCols {
+		return nil, errorf(CodeDimensionMismatch, "add", "cannot add a %dx%d and a %dx%d matrix", rows, cols, bRows, bCols)
+	}
+
+	result := newMatrix(rows, cols)
+	for i := range result {
+		for j := range result[i] {
+			result[i][j] = a[i][j] + b[i][j]
+		}
+	}
+
+	return result, result.finite("add")
+}
+
+// Multiply returns the product of a and b, b should have as many rows as a
+// has columns.
+func Multiply(a, b Matrix) (Matrix, error) {
+	rows, inner, err := a.dims("multiply")
+	if err != nil {
+		return nil, err
+	}
+	bRows, cols, err := b.dims("multiply")
+	if err != nil {
+		return nil, err
+	}
+	if inner != bRows {
+		return nil, errorf(CodeDimensionMismatch, "multiply", "cannot multiply a %dx%d by a %dx%d matrix", rows, inner, bRows, cols)
+	}
+
+	result := newMatrix(rows, cols)
+	for i := range result {
+		for k := 0; k < inner; k++ {
+			for j := range result[i] {
+				result[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+
+	return result, result.finite("multiply")
+}
+
+// Transpose returns the transpose of m.
+func Transpose(m Matrix) (Matrix, error) {
+	rows, cols, err := m.dims("transpose")
+	if err != nil {
+		return nil, err
+	}
+
+	result := newMatrix(cols, rows)
+	for i := range m {
+		for j := range m[i] {
+			result[j][i] = m[i][j]
+		}
+	}
+
+	return result, nil
+}
+
+// lu is the decomposition PA = LU of a square matrix, L and U stored in the
+// same matrix, the unit diagonal of L omitted.
+type lu struct {
+	m    Matrix
+	perm []int
+	// sign of the permutation, for the determinant
+	sign float64
+	// singular is set when a pivot is negligible next to the largest number
+	// of its row, so that a row of small numbers is not compared with the
+	// others
+	singular bool
+}
+
+func decompose(a Matrix) *lu {
+	n := len(a)
+	d := &lu{m: newMatrix(n, n), perm: make([]int, n), sign: 1}
+
+	// The largest number of each row, which follows the row when it is
+	// swapped
+	scales := make([]float64, n)
+	for i := range a {
+		copy(d.m[i], a[i])
+		d.perm[i] = i
+		for _, x := range a[i] {
+			scales[i] = max(scales[i], math.Abs(x))
+		}
+	}
+
+	m := d.m
+	for k := 0; k < n; k++ {
+		// Partial pivoting: the largest number of the column, for stability
+		pivot := k
+		for i := k + 1; i < n; i++ {
+			if math.Abs(m[i][k]) > math.Abs(m[pivot][k]) {
+				pivot = i
+			}
+		}
+		if pivot != k {
+			m[k], m[pivot] = m[pivot], m[k]
+			d.perm[k], d.perm[pivot] = d.perm[pivot], d.perm[k]
+			d.sign = -d.sign
+		}
+
+		if math.Abs(m[k][k]) <= float64(n)*scales[d.perm[k]]*0x1p-52 {
+			d.singular = true
+			m[k][k] = 0
+			continue
+		}
+
+		for i := k + 1; i < n; i++ {
+			m[i][k] /= m[k][k]
+			for j := k + 1; j < n; j++ {
+				m[i][j] -= m[i][k] * m[k][j]
+			}
+		}
+	}
+
+	return d
+}
+
+// solve returns x such that Ax = b, A being non-singular.
+func (d *lu) solve(b Vector) Vector {
+	n := len(d.m)
+	x := make(Vector, n)
+
+	// Ly = Pb
+	for i := 0; i < n; i++ {
+		x[i] = b[d.perm[i]]
+		for j := 0; j < i; j++ {
+			x[i] -= d.m[i][j] * x[j]
+		}
+	}
+	// Ux = y
+	for i := n - 1; i >= 0; i-- {
+		for j := i + 1; j < n; j++ {
+			x[i] -= d.m[i][j] * x[j]
+		}
+		x[i] /= d.m[i][i]
+	}
+
+	return x
+}
+
+// Determinant returns the determinant of the square matrix m, 0 when m is
+// singular.
+func Determinant(m Matrix) (float64, error) {
+	if _, err := m.square("determinant"); err != nil {
+		return 0, err
+	}
+
+	d := decompose(m)
+	if d.singular {
+		return 0, nil
+	}
+
+	result := d.sign
+	for i := range d.m {
+		result *= d.m[i][i]
+	}
+
+	return result, finite("determinant", result)
+}
+
+// Inverse returns the inverse of the square matrix m.
+func Inverse(m Matrix) (Matrix, error) {
+	n, err := m.square("inverse")
+	if err != nil {
+		return nil, err
+	}
+
+	d := decompose(m)
+	if d.singular {
+		return nil, errorf(CodeSingular, "inverse", "the matrix is singular")
+	}
+
+	result := newMatrix(n, n)
+	for j := 0; j < n; j++ {
+		unit := make(Vector, n)
+		unit[j] = 1
+		for i, x := range d.solve(unit) {
+			result[i][j] = x
+		}
+	}
+
+	return result, result.finite("inverse")
+}
+
+// Solve returns x such that ax = b, a being a square non-singular matrix.
+func Solve(a Matrix, b Vector) (Vector, error) {
+	n, err := a.square("solve")
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != n {
+		return nil, errorf(CodeDimensionMismatch, "solve", "b has %d numbers, expected %d", len(b), n)
+	}
+
+	d := decompose(a)
+	if d.singular {
+		return nil, errorf(CodeSingular, "solve", "the matrix is singular, the system has no unique solution")
+	}
+
+	x := d.solve(b)
+
+	return x, finite("solve", x...)
+}
@@ -0,0 +1,184 @@
+package linalg
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestVectors(t *testing.T) {
+	dot, err := Dot(Vector{1, 2, 3}, Vector{4, -5, 6})
+	if err != nil || dot != 12 {
+		t.Errorf("Dot = %v, %v, expected 12", dot, err)
+	}
+
+	cross, err := Cross(Vector{1, 0, 0}, Vector{0, 1, 0})
+	if err != nil || !slices.Equal(cross, Vector{0, 0, 1}) {
+		t.Errorf("Cross = %v, %v, expected [0 0 1]", cross, err)
+	}
+
+	norm, err := Norm(Vector{3, 4})
+	if err != nil || norm != 5 {
+		t.Errorf("Norm = %v, %v, expected 5", norm, err)
+	}
+
+	// The squares would overflow without math.Hypot
+	norm, err = Norm(Vector{3e200, 4e200})
+	if err != nil || math.Abs(norm-5e200) > 1e186 {
+		t.Errorf("Norm of large numbers = %v, %v, expected 5e200", norm, err)
+	}
+}
+
+func TestMatrices(t *testing.T) {
+	a := Matrix{{1, 2}, {3, 4}}
+	b := Matrix{{5, 6}, {7, 8}}
+
+	tests := []struct {
+		name     string
+		fn       func() (Matrix, error)
+		expected Matrix
+	}{
+		{"Add", func() (Matrix, error) { return Add(a, b) }, Matrix{{6, 8}, {10, 12}}},
+		{"Multiply", func() (Matrix, error) { return Multiply(a, b) }, Matrix{{19, 22}, {43, 50}}},
+		{"Multiply 2x3 by 3x1", func() (Matrix, error) { return Multiply(Matrix{{1, 2, 3}, {4, 5, 6}}, Matrix{{1}, {0}, {-1}}) }, Matrix{{-2}, {-2}}},
+		{"Transpose", func() (Matrix, error) { return Transpose(Matrix{{1, 2, 3}, {4, 5, 6}}) }, Matrix{{1, 4}, {2, 5}, {3, 6}}},
+		{"Inverse", func() (Matrix, error) { return Inverse(a) }, Matrix{{-2, 1}, {1.5, -0.5}}},
+		{"Inverse with pivoting", func() (Matrix, error) { return Inverse(Matrix{{0, 1}, {1, 0}}) }, Matrix{{0, 1}, {1, 0}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.fn()
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !nearMatrix(got, tt.expected) {
+				t.Errorf("got %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDeterminant(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        Matrix
+		expected float64
+	}{
+		{"1x1", Matrix{{-3}}, -3},
+		{"2x2", Matrix{{1, 2}, {3, 4}}, -2},
+		{"permutation", Matrix{{0, 1}, {1, 0}}, -1},
+		{"3x3", Matrix{{2, -3, 1}, {2, 0, -1}, {1, 4, 5}}, 49},
+		{"singular", Matrix{{1, 2}, {2, 4}}, 0},
+		// The elimination leaves a pivot of about 1e-16 rather than 0, which
+		// the tolerance treats as singular
+		{"singular up to rounding", Matrix{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}, 0},
+		// Each pivot is compared with its own row, not with 1e10
+		{"rows of different scales", Matrix{{1e10, 0}, {0, 1e-7}}, 1e3},
+		{"row of small numbers", Matrix{{1e10, 1}, {1e-7, 2e-7}}, 2e3 - 1e-7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Determinant(tt.m)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if math.Abs(got-tt.expected) > 1e-12*max(1, math.Abs(tt.expected)) {
+				t.Errorf("Determinant(%v) = %v, expected %v", tt.m, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        Matrix
+		b        Vector
+		expected Vector
+	}{
+		{"2x2", Matrix{{2, 1}, {1, 3}}, Vector{3, 5}, Vector{0.8, 1.4}},
+		{"3x3", Matrix{{2, 1, -1}, {-3, -1, 2}, {-2, 1, 2}}, Vector{8, -11, -3}, Vector{2, 3, -1}},
+		{"zero on the diagonal", Matrix{{0, 2}, {3, 0}}, Vector{4, 9}, Vector{3, 2}},
+		{"rows of different scales", Matrix{{1e10, 0}, {0, 1e-7}}, Vector{2e10, 3e-7}, Vector{2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Solve(tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !nearMatrix(Matrix{got}, Matrix{tt.expected}) {
+				t.Errorf("Solve = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	square := Matrix{{1, 2}, {3, 4}}
+
+	tests := []struct {
+		name      string
+		fn        func() error
+		code      string
+		operation string
+	}{
+		{"dot of empty vectors", func() error { _, err := Dot(Vector{}, Vector{1}); return err }, CodeEmpty, "dot"},
+		{"dot of different lengths", func() error { _, err := Dot(Vector{1, 2}, Vector{1}); return err }, CodeDimensionMismatch, "dot"},
+		{"cross in 2D", func() error { _, err := Cross(Vector{1, 2}, Vector{3, 4}); return err }, CodeNot3D, "cross"},
+		{"norm of nothing", func() error { _, err := Norm(Vector{}); return err }, CodeEmpty, "norm"},
+		{"dot overflow", func() error { _, err := Dot(Vector{1e300}, Vector{1e300}); return err }, CodeNotFinite, "dot"},
+		{"empty matrix", func() error { _, err := Transpose(Matrix{}); return err }, CodeEmpty, "transpose"},
+		{"ragged matrix", func() error { _, err := Add(Matrix{{1, 2}, {3}}, square); return err }, CodeRaggedMatrix, "add"},
+		{"add mismatch", func() error { _, err := Add(square, Matrix{{1, 2, 3}}); return err }, CodeDimensionMismatch, "add"},
+		{"multiply mismatch", func() error { _, err := Multiply(Matrix{{1, 2, 3}}, square); return err }, CodeDimensionMismatch, "multiply"},
+		{"determinant of 2x3", func() error { _, err := Determinant(Matrix{{1, 2, 3}, {4, 5, 6}}); return err }, CodeNotSquare, "determinant"},
+		{"inverse of singular", func() error { _, err := Inverse(Matrix{{1, 2}, {2, 4}}); return err }, CodeSingular, "inverse"},
+		{"inverse singular up to rounding", func() error { _, err := Inverse(Matrix{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}); return err }, CodeSingular, "inverse"},
+		{"solve of singular", func() error { _, err := Solve(Matrix{{1, 1}, {1, 1}}, Vector{1, 2}); return err }, CodeSingular, "solve"},
+		{"solve mismatch", func() error { _, err := Solve(square, Vector{1, 2, 3}); return err }, CodeDimensionMismatch, "solve"},
+		{"vector too large", func() error { return Vector{1, 2, 3}.CheckSize(2) }, CodeTooLarge, ""},
+		{"matrix too wide", func() error { return Matrix{{1, 2, 3}}.CheckSize(2) }, CodeTooLarge, ""},
+		{"matrix too tall", func() error { return Matrix{{1}, {2}, {3}}.CheckSize(2) }, CodeTooLarge, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fn()
+
+			var linalgErr *Error
+			if !errors.As(err, &linalgErr) {
+				t.Fatalf("got error %v, expected an *Error", err)
+			}
+			if linalgErr.Code != tt.code || linalgErr.Operation != tt.operation {
+				t.Errorf("got code %q and operation %q, expected %q and %q", linalgErr.Code, linalgErr.Operation, tt.code, tt.operation)
+			}
+		})
+	}
+
+	if err := (Matrix{{1, 2}, {3, 4}}).CheckSize(2); err != nil {
+		t.Errorf("CheckSize of a 2x2 matrix: unexpected error %v", err)
+	}
+}
+
+func nearMatrix(got, expected Matrix) bool {
+	if len(got) != len(expected) {
+		return false
+	}
+	for i := range got {
+		if len(got[i]) != len(expected[i]) {
+			return false
+		}
+		for j := range got[i] {
+			if math.Abs(got[i][j]-expected[i][j]) > 1e-12 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
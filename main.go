@@ -3,26 +3,31 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/charmbracelet/log"
 
+	"github.com/NDOY3M4N/api-calculator/config"
+	"github.com/NDOY3M4N/api-calculator/metrics"
+	"github.com/NDOY3M4N/api-calculator/migrations"
 	"github.com/NDOY3M4N/api-calculator/ratelimit"
-	"github.com/NDOY3M4N/api-calculator/repository"
 )
 
-const (
-	port       int    = 3000
-	bucketSize int64  = 5
-	bucketRate int64  = 2
-	dbFileName string = "database.db"
+var (
+	// conf is replaced by the loaded configuration at the start of main
+	conf   = config.Default()
+	logger = slog.New(traceLogHandler{log.New(os.Stderr)})
 )
 
-var logger = slog.New(log.New(os.Stderr))
-
 // @title         Calculator API
 // @version       1.0
 // @description   This is a simple server for Calculator API
@@ -34,49 +39,197 @@ var logger = slog.New(log.New(os.Stderr))
 // @license.name  MIT
 // @license.url   https://github.com/NDOY3M4N/api-calculator/blob/main/LICENSE.md
 //
-// @securitydefinitions.bearerauth BearerAuth
-// @name Authorization
+// @securitydefinitions.apikey ApiKeyAuth
+// @name X-API-Key
 // @in header
 //
+// @securitydefinitions.bearerauth BearerAuth
+//
 // @servers.url http://localhost:3000/api/v1
 // @servers.description Development server
 func main() {
-	db, err := NewDatabaseSqlite(dbFileName)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
+	var (
+		opts config.Options
+		err  error
+	)
+	conf, opts, err = config.Load(os.Args[0], os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		logger.Error("Configuration", slog.String("message", err.Error()))
+		os.Exit(2)
+	}
+
+	if opts.PrintConfig {
+		if err := conf.Print(os.Stdout); err != nil {
+			logger.Error("Print configuration", slog.String("message", err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+
+	logger = newLogger(conf.Log)
+	if opts.File != "" {
+		logger.Info("Configuration loaded", slog.String("file", opts.File))
+	}
+	if conf.JWT.Secret == config.Default().JWT.Secret {
+		logger.Warn("The default JWT secret is used, set JWT_SECRET outside of development")
+	}
+
+	db, dialect, err := NewDatabase(conf.DB)
 	if err != nil {
 		logger.Error("DB Init", slog.String("message", err.Error()))
 		os.Exit(1)
 	}
 	initStorage(db)
-	defer db.Close()
 
+	migrator := migrations.New(db, dialect)
+	if conf.DB.AutoMigrate {
+		err = migrateUp(context.Background(), migrator)
+	} else {
+		err = checkMigrations(context.Background(), migrator)
+	}
 	if err != nil {
-		logger.Error("DB connection", slog.String("message", err.Error()))
+		logger.Error("DB migrations", slog.String("message", err.Error()))
 		os.Exit(1)
 	}
 
 	router := http.NewServeMux()
 
-	repo := repository.New(db)
-	handler := NewHandler(repo).RegisterRoutes(router)
+	repo := NewStore(db, dialect, conf.DB.QueryTimeout)
+
+	revocations := NewRevocationList(repo)
+	if err := revocations.Load(context.Background()); err != nil {
+		logger.Error("Revoked tokens loading", slog.String("message", err.Error()))
+		os.Exit(1)
+	}
+
+	handler := NewHandler(repo, revocations).RegisterRoutes(router)
 
-	bucket := ratelimit.NewTokenBucket(bucketSize, bucketRate)
+	limiter := ratelimit.NewKeyedLimiter(conf.RateLimit.Burst, conf.RateLimit.Rate, conf.RateLimit.IdleTTL)
+	// ctx stops the background goroutines, once the server has shut down
 	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	bucket.Start(ctx)
 
-	stack := CreateStack(AddRequestId, Logger, RateLimit(bucket))
+	shutdownTracing, err := setupTracing(ctx)
+	if err != nil {
+		logger.Error("Tracing initialization", slog.String("message", err.Error()))
+		os.Exit(1)
+	}
+
+	limiter.Start(ctx)
+	revocations.Start(ctx, revocationSyncInterval)
+	metrics.RegisterRateLimiter(limiter)
+
+	accessLog, err := NewAccessLogger(conf.Log.AccessFormat, os.Stderr)
+	if err != nil {
+		logger.Error("Access log initialization", slog.String("message", err.Error()))
+		os.Exit(1)
+	}
+
+	stack := CreateStack(
+		Tracing,
+		Traced("Metrics", Metrics),
+		Traced("AddRequestId", AddRequestId),
+		Traced("Logger", Logger(accessLog)),
+		Traced("CORS", CORS(conf.CORS.Origins)),
+		Traced("RateLimit", RateLimit(limiter, rateLimitKey)),
+	)
+
+	// The metrics and probes are served outside of the stack, so that they
+	// are neither rate limited nor counted as API traffic
+	root := http.NewServeMux()
+	root.Handle("GET /metrics", metrics.Handler())
+	health := NewHealth(repo, limiter, migrator.Latest())
+	health.RegisterRoutes(root)
+	root.Handle("/", stack(handler))
+
 	server := http.Server{
-		Handler: stack(handler),
-		Addr:    fmt.Sprintf(":%d", port),
+		Handler: root,
+		Addr:    conf.Listen,
 	}
 
-	logger.Info(fmt.Sprintf("Server started on port :%d", port))
-	logger.Info(fmt.Sprintf("API documentation available on http://localhost:%d/docs", port))
+	host, port, _ := net.SplitHostPort(conf.Listen)
+	if host == "" {
+		host = "localhost"
+	}
+	logger.Info(fmt.Sprintf("Server started on %s", conf.Listen))
+	logger.Info(fmt.Sprintf("API documentation available on http://%s/docs", net.JoinHostPort(host, port)))
+
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
 
-	if err := server.ListenAndServe(); err != nil {
+	exitCode := 0
+	select {
+	case err := <-serverErr:
 		logger.Error("HTTP server initialization", slog.String("message", err.Error()))
-		os.Exit(1)
+		exitCode = 1
+	case <-signals.Done():
+		// A second signal kills the process right away
+		stop()
+		logger.Info("Shutting down, draining in-flight requests",
+			slog.Duration("drainDelay", conf.Shutdown.DrainDelay),
+			slog.Duration("timeout", conf.Shutdown.Timeout),
+		)
+
+		health.SetDraining()
+		time.Sleep(conf.Shutdown.DrainDelay)
+
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), conf.Shutdown.Timeout)
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error("HTTP server shutdown", slog.String("message", err.Error()))
+			exitCode = 1
+		}
+		cancelShutdown()
 	}
+
+	shutdown(cancel, shutdownTracing, db)
+	os.Exit(exitCode)
+}
+
+// shutdown releases what main started once no request is running anymore:
+// the background goroutines, the pending spans and the database.
+func shutdown(cancel context.CancelFunc, shutdownTracing func(context.Context) error, db *sql.DB) {
+	cancel()
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Error("Tracing shutdown", slog.String("message", err.Error()))
+	}
+
+	if err := db.Close(); err != nil {
+		logger.Error("DB close", slog.String("message", err.Error()))
+	}
+
+	logger.Info("Server stopped")
+	os.Stderr.Sync()
+}
+
+func newLogger(c config.Log) *slog.Logger {
+	level, _ := log.ParseLevel(c.Level)
+	formatter := log.TextFormatter
+	switch c.Format {
+	case "logfmt":
+		formatter = log.LogfmtFormatter
+	case "json":
+		formatter = log.JSONFormatter
+	}
+
+	return slog.New(traceLogHandler{log.NewWithOptions(os.Stderr, log.Options{
+		Level:           level,
+		Formatter:       formatter,
+		ReportTimestamp: c.Format != "text",
+	})})
 }
 
 func initStorage(db *sql.DB) {
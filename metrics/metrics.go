@@ -0,0 +1,94 @@
+// Package metrics holds the Prometheus collectors of the API and the handler
+// exposing them in the text exposition format.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "calculator"
+
+// Registry is used instead of the default registerer so that only the
+// collectors below, plus the Go runtime and process ones, are exposed.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests handled, by route and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the HTTP requests, by route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	RateLimitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ratelimit_rejections_total",
+		Help:      "Number of requests rejected by the rate limiter, by kind of key (user or ip).",
+	}, []string{"kind"})
+
+	OperationsRecorded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "operations_recorded_total",
+		Help:      "Number of operations stored, by type and mode.",
+	}, []string{"type", "mode"})
+
+	AuthFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_failures_total",
+		Help:      "Number of rejected authentication attempts, by reason.",
+	}, []string{"reason"})
+
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of the repository methods, by method.",
+		Buckets:   []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"query"})
+)
+
+// Reasons of AuthFailures
+const (
+	ReasonMissingCredentials = "missing_credentials"
+	ReasonInvalidToken       = "invalid_token"
+	ReasonRevokedToken       = "revoked_token"
+	ReasonInvalidAPIKey      = "invalid_api_key"
+	ReasonUnknownUser        = "unknown_user"
+	ReasonMissingScope       = "missing_scope"
+	ReasonInvalidPassword    = "invalid_password"
+	ReasonAccountLocked      = "account_locked"
+	ReasonInvalidRefresh     = "invalid_refresh_token"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		RateLimitRejections,
+		OperationsRecorded,
+		AuthFailures,
+		DBQueryDuration,
+	)
+}
+
+// ObserveQuery records the time spent in query since start. It is meant to
+// be deferred at the top of the repository methods.
+func ObserveQuery(query string, start time.Time) {
+	DBQueryDuration.WithLabelValues(query).Observe(time.Since(start).Seconds())
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/NDOY3M4N/api-calculator/ratelimit"
+)
+
+// fillBuckets are the upper bounds of the fill ratio histogram, 0 being an
+// empty bucket and 1 a full one.
+var fillBuckets = []float64{0, .25, .5, .75, 1}
+
+// rateLimitCollector reads the state of the limiter at scrape time. There is
+// one bucket per caller, so the fill levels are exposed as a histogram rather
+// than one series per key.
+type rateLimitCollector struct {
+	limiter *ratelimit.KeyedLimiter
+
+	buckets *prometheus.Desc
+	fill    *prometheus.Desc
+}
+
+// RegisterRateLimiter exposes the number of buckets of limiter and the
+// distribution of their fill level.
+func RegisterRateLimiter(limiter *ratelimit.KeyedLimiter) {
+	Registry.MustRegister(&rateLimitCollector{
+		limiter: limiter,
+		buckets: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "ratelimit", "buckets"),
+			"Number of token buckets currently tracked by the rate limiter.",
+			nil, nil,
+		),
+		fill: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "ratelimit", "bucket_fill_ratio"),
+			"Fill level of the token buckets, as a ratio of their capacity.",
+			nil, nil,
+		),
+	})
+}
+
+func (c *rateLimitCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.buckets
+	ch <- c.fill
+}
+
+func (c *rateLimitCollector) Collect(ch chan<- prometheus.Metric) {
+	levels := c.limiter.Levels()
+	capacity := float64(c.limiter.Capacity())
+
+	counts := make(map[float64]uint64, len(fillBuckets))
+	var sum float64
+	for _, level := range levels {
+		ratio := float64(level) / capacity
+		sum += ratio
+		for _, bound := range fillBuckets {
+			if ratio <= bound {
+				counts[bound]++
+			}
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.buckets, prometheus.GaugeValue, float64(len(levels)))
+	ch <- prometheus.MustNewConstHistogram(c.fill, uint64(len(levels)), sum, counts)
+}
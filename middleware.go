@@ -1,18 +1,23 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"crypto/rand"
-	"encoding/hex"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
-
+	"github.com/NDOY3M4N/api-calculator/metrics"
 	"github.com/NDOY3M4N/api-calculator/ratelimit"
 	"github.com/NDOY3M4N/api-calculator/repository"
 )
@@ -22,25 +27,221 @@ type contextKey string
 const (
 	requestIDKey contextKey = "requestID"
 	userIDKey    contextKey = "userID"
+	claimsKey    contextKey = "claims"
+	apiKeyKey    contextKey = "apiKey"
+	scopesKey    contextKey = "scopes"
+	infoKey      contextKey = "requestInfo"
 )
 
+var ErrTokenRevoked = errors.New("token revoked")
+
 type Middleware func(http.HandlerFunc) http.HandlerFunc
 
+// wrapperWritter records the status code and the size of the response. It
+// forwards Flush and Hijack, and Unwrap lets http.ResponseController reach
+// the other optional methods of the wrapped writer.
 type wrapperWritter struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode  int
+	bytes       int64
+	wroteHeader bool
+}
+
+var (
+	_ http.Flusher  = (*wrapperWritter)(nil)
+	_ http.Hijacker = (*wrapperWritter)(nil)
+)
+
+func newWrapperWritter(w http.ResponseWriter) *wrapperWritter {
+	return &wrapperWritter{ResponseWriter: w, statusCode: http.StatusOK}
 }
 
 func (w *wrapperWritter) WriteHeader(code int) {
-	w.statusCode = code
+	if !w.wroteHeader {
+		w.statusCode = code
+		w.wroteHeader = true
+	}
 	w.ResponseWriter.WriteHeader(code)
 }
 
-func IsAuthenticated(repo *repository.Repository) Middleware {
+func (w *wrapperWritter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+
+	return n, err
+}
+
+func (w *wrapperWritter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		f.Flush()
+	}
+}
+
+func (w *wrapperWritter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T does not support hijacking", w.ResponseWriter)
+	}
+
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.statusCode = http.StatusSwitchingProtocols
+		w.wroteHeader = true
+	}
+
+	return conn, rw, err
+}
+
+func (w *wrapperWritter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// requestInfo is shared by the middlewares of a request, so that the outer
+// ones can read what was only known after routing.
+type requestInfo struct {
+	// route is the pattern that matched the request, "" when none did
+	route string
+	// userID is the authenticated caller, 0 for anonymous requests
+	userID int
+}
+
+// withRequestInfo returns the requestInfo of r, adding one to its context if
+// there is none yet.
+func withRequestInfo(r *http.Request) (*http.Request, *requestInfo) {
+	if info, ok := r.Context().Value(infoKey).(*requestInfo); ok {
+		return r, info
+	}
+
+	info := new(requestInfo)
+	return r.WithContext(context.WithValue(r.Context(), infoKey, info)), info
+}
+
+// recordRoute stores in the requestInfo the pattern of mux matching the
+// request, mux being mounted under prefix.
+func recordRoute(prefix string, mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info, ok := r.Context().Value(infoKey).(*requestInfo); ok {
+			if _, pattern := mux.Handler(r); pattern != "" {
+				// Drop the method, it has its own label
+				_, path, _ := strings.Cut(pattern, " ")
+				if path == "" {
+					path = pattern
+				}
+				info.route = prefix + path
+			}
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// Metrics counts and times the requests by route and status code. It should
+// come first in the stack so that rejected requests are counted too.
+func Metrics(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r, info := withRequestInfo(r)
+
+		start := time.Now()
+		wrappedWritter := newWrapperWritter(w)
+		next.ServeHTTP(wrappedWritter, r)
+
+		route := info.route
+		if route == "" {
+			// Keep unknown paths out of the labels
+			route = "unmatched"
+		}
+		status := strconv.Itoa(wrappedWritter.statusCode)
+		metrics.HTTPRequests.WithLabelValues(r.Method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(r.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// CORS lets the browsers of origins call the API, "*" allowing any origin.
+// Preflight requests are answered here, before the rate limiter and the
+// authentication. It does nothing when origins is empty.
+func CORS(origins []string) Middleware {
+	allowAny := slices.Contains(origins, "*")
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		if len(origins) == 0 {
+			return next
+		}
+
+		return func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" || (!allowAny && !slices.Contains(origins, origin)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Origin")
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After")
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-API-Key, X-Request-ID, X-Calc-Mode, X-Calc-Precision, X-Calc-Rounding, traceparent")
+				w.Header().Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+	}
+}
+
+// IsAuthenticated accepts either a JWT in the Authorization header or an API
+// key in the X-API-Key header, and stores the ID of the caller in the context.
+func IsAuthenticated(repo repository.Store, revocations *RevocationList) Middleware {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
+			if key := r.Header.Get(apiKeyHeader); key != "" {
+				apiKey, err := authenticateAPIKey(r.Context(), repo, key)
+				if err != nil {
+					if errors.Is(err, ErrInvalidAPIKey) {
+						metrics.AuthFailures.WithLabelValues(metrics.ReasonInvalidAPIKey).Inc()
+						writeError(w, r, http.StatusUnauthorized, err)
+						return
+					}
+					writeError(w, r, http.StatusInternalServerError, err)
+					return
+				}
+
+				userID := int(apiKey.UserId)
+				user, err := repo.FindUserById(r.Context(), userID)
+				if err != nil {
+					metrics.AuthFailures.WithLabelValues(metrics.ReasonUnknownUser).Inc()
+					writeError(w, r, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+					return
+				}
+
+				if now := time.Now(); apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+					if err := repo.TouchAPIKey(r.Context(), apiKey.Id, now); err != nil {
+						writeError(w, r, http.StatusInternalServerError, err)
+						return
+					}
+				}
+
+				// A key cannot do more than its owner's current role allows
+				scopes := intersectScopes(apiKey.Scopes, scopesForRole(user.Role))
+
+				if info, ok := r.Context().Value(infoKey).(*requestInfo); ok {
+					info.userID = userID
+				}
+
+				ctx := context.WithValue(r.Context(), userIDKey, userID)
+				ctx = context.WithValue(ctx, apiKeyKey, apiKey)
+				ctx = context.WithValue(ctx, scopesKey, scopes)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
 			header := r.Header.Get("Authorization")
 			if header == "" {
+				metrics.AuthFailures.WithLabelValues(metrics.ReasonMissingCredentials).Inc()
 				writeError(
 					w,
 					r,
@@ -50,8 +251,9 @@ func IsAuthenticated(repo *repository.Repository) Middleware {
 				return
 			}
 
-			token, err := ValidateToken(strings.TrimPrefix(header, "Bearer "))
+			claims, err := ValidateToken(strings.TrimPrefix(header, "Bearer "))
 			if err != nil {
+				metrics.AuthFailures.WithLabelValues(metrics.ReasonInvalidToken).Inc()
 				writeError(
 					w,
 					r,
@@ -61,45 +263,84 @@ func IsAuthenticated(repo *repository.Repository) Middleware {
 				return
 			}
 
-			if !token.Valid {
-				writeError(w, r, http.StatusForbidden, fmt.Errorf("token invalid"))
+			userID, err := claims.UserID()
+			if err != nil {
+				metrics.AuthFailures.WithLabelValues(metrics.ReasonInvalidToken).Inc()
+				writeError(w, r, http.StatusForbidden, err)
 				return
 			}
 
-			var userIDStr string
-			if claims, ok := token.Claims.(jwt.MapClaims); ok {
-				userIDStr = claims["userID"].(string)
-			} else {
-				writeError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to get claims from token"))
+			if revocations.IsRevoked(claims.ID) {
+				metrics.AuthFailures.WithLabelValues(metrics.ReasonRevokedToken).Inc()
+				writeError(w, r, http.StatusUnauthorized, ErrTokenRevoked)
 				return
 			}
 
-			userID, _ := strconv.Atoi(userIDStr)
-			if _, err = repo.FindUserById(userID); err != nil {
+			user, err := repo.FindUserById(r.Context(), userID)
+			if err != nil {
+				metrics.AuthFailures.WithLabelValues(metrics.ReasonUnknownUser).Inc()
 				writeError(w, r, http.StatusUnauthorized, fmt.Errorf("permission denied"))
 				return
 			}
 
+			if user.TokensValidAfter != nil && (claims.IssuedAt == nil || claims.IssuedAt.Before(*user.TokensValidAfter)) {
+				metrics.AuthFailures.WithLabelValues(metrics.ReasonRevokedToken).Inc()
+				writeError(w, r, http.StatusUnauthorized, ErrTokenRevoked)
+				return
+			}
+
+			if info, ok := r.Context().Value(infoKey).(*requestInfo); ok {
+				info.userID = userID
+			}
+
 			ctx := context.WithValue(r.Context(), userIDKey, userID)
+			ctx = context.WithValue(ctx, claimsKey, claims)
+			ctx = context.WithValue(ctx, scopesKey, scopesForRole(claims.Role))
 			next.ServeHTTP(w, r.WithContext(ctx))
 		}
 	}
 }
 
-func RateLimit(tb *ratelimit.TokenBucket) Middleware {
+// RequireScope rejects with a 403 the requests whose credential was not
+// granted scope. It should run after IsAuthenticated.
+func RequireScope(scope string) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !hasScope(r, scope) {
+				metrics.AuthFailures.WithLabelValues(metrics.ReasonMissingScope).Inc()
+				writeError(w, r, http.StatusForbidden, &MissingScopeError{scope})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+	}
+}
+
+func hasScope(r *http.Request, scope string) bool {
+	scopes, _ := r.Context().Value(scopesKey).([]string)
+	return slices.Contains(scopes, scope)
+}
+
+// RateLimit consumes a token from the caller's own bucket: the user of a
+// verified API key or of a valid token, the client IP otherwise. The
+// request is rejected right away with a 429 when the bucket is empty.
+func RateLimit(limiter *ratelimit.KeyedLimiter, key func(*http.Request) string) Middleware {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
-			reqID := r.Context().Value(requestIDKey).(string)
-			tb.Consume()
+			reqID := requestID(r)
+			bucketKey := key(r)
+			res := limiter.Bucket(bucketKey).TryConsume()
 
-			remaining := len(tb.Tokens)
-			reset := time.Now().Add(time.Second).Unix()
-			w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", bucketSize))
-			w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining))
-			w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", reset))
+			w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", limiter.Capacity()))
+			w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", res.Remaining))
+			w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", res.Reset.Unix()))
 
-			if remaining == 0 {
-				logger.Warn("Rate limit exceeded. Please wait before making more requests.",
+			if !res.Allowed {
+				kind, _, _ := strings.Cut(bucketKey, ":")
+				metrics.RateLimitRejections.WithLabelValues(kind).Inc()
+
+				logger.WarnContext(r.Context(), "Rate limit exceeded. Please wait before making more requests.",
 					slog.Int("statusCode", http.StatusTooManyRequests),
 					slog.String("remoteAddr", r.RemoteAddr),
 					slog.Group("request",
@@ -109,7 +350,9 @@ func RateLimit(tb *ratelimit.TokenBucket) Middleware {
 					),
 				)
 
-				w.Header().Set("Retry-After", "1")
+				// Retry-After is expressed in whole seconds, rounded up
+				retryAfter := int64(math.Ceil(res.RetryAfter.Seconds()))
+				w.Header().Set("Retry-After", fmt.Sprintf("%d", max(retryAfter, 1)))
 				w.WriteHeader(http.StatusTooManyRequests)
 				return
 			}
@@ -119,9 +362,56 @@ func RateLimit(tb *ratelimit.TokenBucket) Middleware {
 	}
 }
 
+// rateLimitKey identifies the caller of a request: the owner of an API key
+// already verified by IsAuthenticated, the user of a valid token, or the
+// client IP. A forged key is not verified and draws from the bucket of the
+// IP, so that it cannot get a fresh bucket, and the keys of a user share the
+// bucket of the user. The database is never queried here.
+func rateLimitKey(r *http.Request) string {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		if userID, ok := verifiedAPIKeys.userID(key); ok {
+			return "user:" + strconv.FormatInt(userID, 10)
+		}
+	} else if header := r.Header.Get("Authorization"); header != "" {
+		claims, err := ValidateToken(strings.TrimPrefix(header, "Bearer "))
+		if err == nil {
+			if userID, err := claims.UserID(); err == nil {
+				return "user:" + strconv.Itoa(userID)
+			}
+		}
+	}
+
+	return "ip:" + clientIP(r)
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// requestIDPattern restricts the inbound request IDs to what can be logged
+// and stored safely.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// traceparentPattern matches a W3C traceparent header, capturing its version
+// and trace ID.
+var traceparentPattern = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}`)
+
+// AddRequestId reuses the request ID set by the caller, either in the
+// X-Request-ID header or as the trace ID of the traceparent header, and
+// generates one otherwise. Invalid inbound IDs are ignored.
 func AddRequestId(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		reqID, err := generateRequestID()
+		reqID := inboundRequestID(r)
+
+		var err error
+		if reqID == "" {
+			reqID, err = generateRequestID()
+		}
 		ctx := context.WithValue(r.Context(), requestIDKey, reqID)
 		r = r.WithContext(ctx)
 
@@ -136,28 +426,6 @@ func AddRequestId(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-func Logger(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		reqID := r.Context().Value(requestIDKey).(string)
-
-		start := time.Now()
-		wrappedWritter := &wrapperWritter{w, http.StatusOK}
-
-		logger.Info("Log request",
-			slog.Int("statusCode", wrappedWritter.statusCode),
-			slog.Duration("duration", time.Since(start)),
-			slog.String("remoteAddr", r.RemoteAddr),
-			slog.Group("request",
-				slog.String("id", reqID),
-				slog.String("method", r.Method),
-				slog.String("path", r.URL.Path),
-			),
-		)
-
-		next.ServeHTTP(wrappedWritter, r)
-	}
-}
-
 func CreateStack(xs ...Middleware) Middleware {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		for i := len(xs) - 1; i >= 0; i-- {
@@ -169,12 +437,36 @@ func CreateStack(xs ...Middleware) Middleware {
 	}
 }
 
+func inboundRequestID(r *http.Request) string {
+	if reqID := r.Header.Get("X-Request-ID"); requestIDPattern.MatchString(reqID) {
+		return reqID
+	}
+
+	m := traceparentPattern.FindStringSubmatch(r.Header.Get("traceparent"))
+	if m != nil && m[1] != "ff" && m[2] != strings.Repeat("0", 32) {
+		return m[2]
+	}
+
+	return ""
+}
+
+// generateRequestID returns a UUIDv7: its first 48 bits are the time in
+// milliseconds, so that the IDs sort by creation, and 74 are random.
 func generateRequestID() (string, error) {
-	b := make([]byte, 3)
-	_, err := rand.Read(b)
-	if err != nil {
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
 		return "", fmt.Errorf("Error generating request ID: %s", err)
 	}
 
-	return "req_" + hex.EncodeToString(b), nil
+	binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixMilli())<<16|uint64(binary.BigEndian.Uint16(b[6:8])))
+	b[6] = b[6]&0x0f | 0x70 // version 7
+	b[8] = b[8]&0x3f | 0x80 // RFC 9562 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// requestID returns the ID set by AddRequestId, or "" outside of the stack.
+func requestID(r *http.Request) string {
+	reqID, _ := r.Context().Value(requestIDKey).(string)
+	return reqID
 }
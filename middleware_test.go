@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NDOY3M4N/api-calculator/ratelimit"
+	"github.com/NDOY3M4N/api-calculator/repository"
+)
+
+func TestRateLimitKey(t *testing.T) {
+	key, prefix, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, _, _, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifiedAPIKeys.add(&repository.APIKey{Id: 1, UserId: 3, Prefix: prefix, KeyHash: hash})
+	t.Cleanup(func() { verifiedAPIKeys.forgetID(1) })
+
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{"no credentials", "", "ip:192.0.2.1"},
+		{"verified key", key, "user:3"},
+		{"forged key", forged, "ip:192.0.2.1"},
+		{"known prefix with another secret", "calc_" + prefix + "_forged", "ip:192.0.2.1"},
+		{"malformed key", "calc_x", "ip:192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/add", nil)
+			r.RemoteAddr = "192.0.2.1:41234"
+			if tt.header != "" {
+				r.Header.Set(apiKeyHeader, tt.header)
+			}
+
+			if got := rateLimitKey(r); got != tt.expected {
+				t.Errorf("got bucket %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestRateLimitForgedKeys checks that forging a new key on every request does
+// not get around the limit of the IP.
+func TestRateLimitForgedKeys(t *testing.T) {
+	limiter := ratelimit.NewKeyedLimiter(1, 1, time.Minute)
+	handler := RateLimit(limiter, rateLimitKey)(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	for i, expected := range []int{http.StatusNoContent, http.StatusTooManyRequests} {
+		key, _, _, err := GenerateAPIKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/login", nil)
+		r.RemoteAddr = "192.0.2.1:41234"
+		r.Header.Set(apiKeyHeader, key)
+		w := httptest.NewRecorder()
+		handler(w, r)
+
+		if w.Code != expected {
+			t.Errorf("request %d: got status %d, expected %d", i+1, w.Code, expected)
+		}
+	}
+
+	if got := limiter.Len(); got != 1 {
+		t.Errorf("got %d buckets, expected only the one of the IP", got)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/NDOY3M4N/api-calculator/config"
+	"github.com/NDOY3M4N/api-calculator/migrations"
+)
+
+const migrateUsage = "usage: %s migrate up|down|status [flags]\n"
+
+// runMigrate runs the migrate command, which applies (up), rolls back the last
+// (down) or lists (status) the migrations, and returns the exit code. It
+// takes the same flags as the server.
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, migrateUsage, os.Args[0])
+		return 2
+	}
+	action := args[0]
+
+	var err error
+	conf, _, err = config.Load(os.Args[0]+" migrate "+action, args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		logger.Error("Configuration", slog.String("message", err.Error()))
+		return 2
+	}
+	logger = newLogger(conf.Log)
+
+	db, dialect, err := NewDatabase(conf.DB)
+	if err != nil {
+		logger.Error("DB Init", slog.String("message", err.Error()))
+		return 1
+	}
+	defer db.Close()
+
+	migrator := migrations.New(db, dialect)
+	ctx := context.Background()
+
+	switch action {
+	case "up":
+		err = migrateUp(ctx, migrator)
+	case "down":
+		var migration *migrations.Migration
+		if migration, err = migrator.Down(ctx); err == nil {
+			if migration == nil {
+				logger.Info("No migration to roll back")
+			} else {
+				logger.Info("Migration rolled back", slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+			}
+		}
+	case "status":
+		err = printMigrations(ctx, migrator)
+	default:
+		fmt.Fprintf(os.Stderr, migrateUsage, os.Args[0])
+		return 2
+	}
+	if err != nil {
+		logger.Error("Migrations", slog.String("message", err.Error()))
+		return 1
+	}
+
+	return 0
+}
+
+// migrateUp applies the pending migrations. It fails when the database was
+// migrated by a newer binary, which the server should not run against.
+func migrateUp(ctx context.Context, migrator *migrations.Migrator) error {
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range applied {
+		logger.Info("Migration applied", slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+	}
+	logger.Info("DB schema up to date", slog.Int64("version", migrator.Latest()))
+
+	return nil
+}
+
+// checkMigrations refuses to start the server on a database with pending
+// migrations, when they are not applied at startup. It fails as well when the
+// database is newer.
+func checkMigrations(ctx context.Context, migrator *migrations.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, status := range statuses {
+		if status.AppliedAt == "" {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("DB schema is not up to date: %d pending migrations, run the migrate up command or enable db.auto_migrate", pending)
+	}
+
+	return nil
+}
+
+func printMigrations(ctx context.Context, migrator *migrations.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := status.AppliedAt
+		if appliedAt == "" {
+			appliedAt = "pending"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,465 @@
+// Package migrations embeds the SQL migrations of the database and applies
+// them. The files keep the goose format: the statements after "-- +goose Up"
+// apply a migration, the ones after "-- +goose Down" roll it back.
+//
+// Each dialect has its own migrations, in the directory of its name. The
+// applied versions are stored in the schema_version table. Databases migrated
+// with the goose CLI are adopted on the first run, from their
+// goose_db_version table.
+package migrations
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+//go:embed sqlite/*.sql postgres/*.sql
+var files embed.FS
+
+// Dialect is the SQL dialect of a database, it selects its migrations.
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// dialect holds the statements of the migrator that differ between the
+// databases. Their placeholders are ?, rebound when numbered is set.
+type dialect struct {
+	// numbered tells that the placeholders are $1, $2...
+	numbered bool
+	// lock starts the transaction of the migrations and takes a lock that
+	// the other instances wait for
+	lock        []string
+	tableExists string
+	createTable string
+	// appliedAt formats the date of the %s column as text
+	appliedAt string
+}
+
+// lockID identifies the advisory lock of the migrations on PostgreSQL.
+const lockID = 8_402_165_310
+
+var dialects = map[Dialect]dialect{
+	// An IMMEDIATE transaction takes the write lock of the database right
+	// away, the other instances wait for it up to the busy timeout
+	SQLite: {
+		lock:        []string{"BEGIN IMMEDIATE"},
+		tableExists: "SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = ?",
+		createTable: `CREATE TABLE schema_version (
+  version INTEGER PRIMARY KEY,
+  name TEXT NOT NULL,
+  applied_at TEXT NOT NULL DEFAULT (DATETIME('now', 'localtime'))
+)`,
+		appliedAt: "DATETIME(%s)",
+	},
+	// The advisory lock is released with the transaction
+	Postgres: {
+		numbered:    true,
+		lock:        []string{"BEGIN", fmt.Sprintf("SELECT pg_advisory_xact_lock(%d)", lockID)},
+		tableExists: "SELECT COUNT(*) > 0 FROM pg_catalog.pg_tables WHERE schemaname = CURRENT_SCHEMA() AND tablename = ?",
+		createTable: `CREATE TABLE schema_version (
+  version BIGINT PRIMARY KEY,
+  name TEXT NOT NULL,
+  applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+)`,
+		appliedAt: "TO_CHAR(%s, 'YYYY-MM-DD HH24:MI:SS')",
+	},
+}
+
+// ErrDatabaseNewer is returned when the database has migrations applied that
+// this binary does not know about, e.g. after rolling back a deployment.
+var ErrDatabaseNewer = errors.New("database schema is newer than this binary")
+
+type Migration struct {
+	Version int64
+	Name    string
+
+	up   string
+	down string
+}
+
+// Status is a migration with the date it was applied, empty when it is pending.
+type Status struct {
+	Migration
+	AppliedAt string
+}
+
+// all are the embedded migrations of each dialect, by increasing version.
+var all = map[Dialect][]Migration{
+	SQLite:   mustParse(files, SQLite),
+	Postgres: mustParse(files, Postgres),
+}
+
+// Latest returns the version of the last embedded migration of d, which the
+// database should be at for the binary to work.
+func Latest(d Dialect) int64 {
+	return all[d][len(all[d])-1].Version
+}
+
+func mustParse(fsys fs.FS, d Dialect) []Migration {
+	names, err := fs.Glob(fsys, string(d)+"/*.sql")
+	if err != nil {
+		panic(err)
+	}
+
+	var migrations []Migration
+	for _, name := range names {
+		m, err := parse(fsys, name)
+		if err != nil {
+			panic(fmt.Sprintf("migration %s: %v", name, err))
+		}
+		migrations = append(migrations, m)
+	}
+	if len(migrations) == 0 {
+		panic("no migration embedded for " + string(d))
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return migrations
+}
+
+// parse reads a file named <version>_<name>.sql.
+func parse(fsys fs.FS, name string) (Migration, error) {
+	version, title, ok := strings.Cut(strings.TrimSuffix(path.Base(name), ".sql"), "_")
+	if !ok {
+		return Migration{}, errors.New("the name should be <version>_<name>.sql")
+	}
+
+	var (
+		m   = Migration{Name: title}
+		err error
+	)
+	if m.Version, err = strconv.ParseInt(version, 10, 64); err != nil || m.Version <= 0 {
+		return Migration{}, fmt.Errorf("invalid version %q", version)
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		return Migration{}, err
+	}
+	defer f.Close()
+
+	var (
+		up, down strings.Builder
+		section  *strings.Builder
+	)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if annotation, ok := strings.CutPrefix(line, "-- +goose "); ok {
+			switch strings.TrimSpace(annotation) {
+			case "Up":
+				section = &up
+			case "Down":
+				section = &down
+			}
+			// StatementBegin and StatementEnd are not needed, as each
+			// section is run as a whole
+			continue
+		}
+		if section != nil {
+			section.WriteString(line + "\n")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Migration{}, err
+	}
+
+	m.up, m.down = strings.TrimSpace(up.String()), strings.TrimSpace(down.String())
+	if m.up == "" {
+		return Migration{}, errors.New("no statement after -- +goose Up")
+	}
+
+	return m, nil
+}
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    dialect
+	migrations []Migration
+}
+
+// New returns the migrator of db, a database of dialect d.
+func New(db *sql.DB, d Dialect) *Migrator {
+	return &Migrator{db: db, dialect: dialects[d], migrations: all[d]}
+}
+
+// Latest returns the version of the last migration of the database.
+func (m *Migrator) Latest() int64 {
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies the pending migrations, in a single transaction, and returns
+// them.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var pending []Migration
+
+	err := m.locked(ctx, func(conn *sql.Conn, applied map[int64]string) error {
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			if _, err := conn.ExecContext(ctx, migration.up); err != nil {
+				return fmt.Errorf("applying migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx, m.rebind("INSERT INTO schema_version (version, name) VALUES (?, ?)"), migration.Version, migration.Name); err != nil {
+				return err
+			}
+			pending = append(pending, migration)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// Down rolls back the last applied migration and returns it, nil when no
+// migration is applied.
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var last *Migration
+
+	err := m.locked(ctx, func(conn *sql.Conn, applied map[int64]string) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := applied[m.migrations[i].Version]; ok {
+				last = &m.migrations[i]
+				break
+			}
+		}
+		if last == nil {
+			return nil
+		}
+		if last.down == "" {
+			return fmt.Errorf("migration %d_%s cannot be rolled back", last.Version, last.Name)
+		}
+
+		if _, err := conn.ExecContext(ctx, last.down); err != nil {
+			return fmt.Errorf("rolling back migration %d_%s: %w", last.Version, last.Name, err)
+		}
+		_, err := conn.ExecContext(ctx, m.rebind("DELETE FROM schema_version WHERE version = ?"), last.Version)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return last, nil
+}
+
+// Status lists the embedded migrations, with the date they were applied. It
+// only reads the database: it takes no lock and does not create the
+// schema_version table, the versions of a database only migrated with goose
+// are read from goose_db_version.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.currentVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkKnown(applied); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		statuses = append(statuses, Status{Migration: migration, AppliedAt: applied[migration.Version]})
+	}
+
+	return statuses, nil
+}
+
+// locked runs fn in a transaction holding the write lock of the database, so
+// that two instances starting together do not apply the same migrations.
+// fn is given the applied versions with the date they were applied. The
+// transaction is rolled back when fn fails, or when the database is newer
+// than the binary.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn, applied map[int64]string) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for i, statement := range m.dialect.lock {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			if i > 0 {
+				conn.ExecContext(context.Background(), "ROLLBACK")
+			}
+			return fmt.Errorf("locking the database: %w", err)
+		}
+	}
+	defer func() {
+		if err != nil {
+			conn.ExecContext(context.Background(), "ROLLBACK")
+			return
+		}
+		if _, err = conn.ExecContext(ctx, "COMMIT"); err != nil {
+			conn.ExecContext(context.Background(), "ROLLBACK")
+		}
+	}()
+
+	if err := m.bootstrap(ctx, conn); err != nil {
+		return err
+	}
+
+	applied, err := m.appliedVersions(ctx, conn, m.schemaVersions())
+	if err != nil {
+		return err
+	}
+	if err := m.checkKnown(applied); err != nil {
+		return err
+	}
+
+	return fn(conn, applied)
+}
+
+// checkKnown fails when a version of applied is not an embedded migration.
+func (m *Migrator) checkKnown(applied map[int64]string) error {
+	for version := range applied {
+		if !slices.ContainsFunc(m.migrations, func(m Migration) bool { return m.Version == version }) {
+			return fmt.Errorf("%w: migration %d is applied but unknown, the latest known is %d", ErrDatabaseNewer, version, m.Latest())
+		}
+	}
+
+	return nil
+}
+
+// bootstrap creates the schema_version table, filled from goose_db_version
+// when the database was migrated with goose.
+func (m *Migrator) bootstrap(ctx context.Context, conn *sql.Conn) error {
+	var exists bool
+	err := conn.QueryRowContext(ctx, m.rebind(m.dialect.tableExists), "schema_version").Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	if _, err = conn.ExecContext(ctx, m.dialect.createTable); err != nil {
+		return err
+	}
+
+	err = conn.QueryRowContext(ctx, m.rebind(m.dialect.tableExists), "goose_db_version").Scan(&exists)
+	if err != nil || !exists {
+		return err
+	}
+
+	// goose keeps a row per change, the last one of a version tells whether
+	// it is applied
+	_, err = conn.ExecContext(ctx, "INSERT INTO schema_version (version, name, applied_at)\n"+fmt.Sprintf(gooseVersions, "tstamp"))
+	if err != nil {
+		return fmt.Errorf("adopting the goose migrations: %w", err)
+	}
+
+	for _, migration := range m.migrations {
+		_, err := conn.ExecContext(ctx, m.rebind("UPDATE schema_version SET name = ? WHERE version = ?"), migration.Name, migration.Version)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// gooseVersions selects the versions applied by goose, with the %s date of
+// their last change. goose keeps a row per change, the last one of a version
+// tells whether it is applied.
+const gooseVersions = `SELECT version_id, '', %s
+  FROM goose_db_version AS g
+  WHERE version_id > 0
+    AND is_applied
+    AND id = (SELECT MAX(id) FROM goose_db_version WHERE version_id = g.version_id)`
+
+func (m *Migrator) schemaVersions() string {
+	return "SELECT version, name, " + fmt.Sprintf(m.dialect.appliedAt, "applied_at") + " FROM schema_version"
+}
+
+// currentVersions reads the applied versions without writing to the
+// database: from schema_version, else from goose_db_version, else none.
+func (m *Migrator) currentVersions(ctx context.Context) (map[int64]string, error) {
+	for _, table := range []string{"schema_version", "goose_db_version"} {
+		var exists bool
+		if err := m.db.QueryRowContext(ctx, m.rebind(m.dialect.tableExists), table).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+
+		if table == "schema_version" {
+			return m.appliedVersions(ctx, m.db, m.schemaVersions())
+		}
+		return m.appliedVersions(ctx, m.db, fmt.Sprintf(gooseVersions, fmt.Sprintf(m.dialect.appliedAt, "tstamp")))
+	}
+
+	return map[int64]string{}, nil
+}
+
+// querier is a *sql.DB or a *sql.Conn.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// appliedVersions runs query, which selects a version, its name and the date
+// it was applied, and returns the dates by version.
+func (m *Migrator) appliedVersions(ctx context.Context, q querier, query string) (map[int64]string, error) {
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]string)
+	for rows.Next() {
+		var (
+			version         int64
+			name, appliedAt string
+		)
+		if err := rows.Scan(&version, &name, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// rebind replaces the ? placeholders of query with $1, $2... when the
+// dialect numbers them.
+func (m *Migrator) rebind(query string) string {
+	if !m.dialect.numbered {
+		return query
+	}
+
+	var (
+		b strings.Builder
+		n int
+	)
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,76 @@
+-- +goose Up
+-- The schema reached by the SQLite migrations up to
+-- 20250308094512_add_request_id_to_operations, without the seeded users.
+-- Dates are TIMESTAMPTZ and JSON columns JSONB.
+CREATE TABLE users (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  pseudo TEXT NOT NULL UNIQUE,
+  password_hash TEXT,
+  failed_login_attempts INTEGER NOT NULL DEFAULT 0,
+  locked_until TIMESTAMPTZ,
+  tokens_valid_after TIMESTAMPTZ,
+  role TEXT NOT NULL DEFAULT 'user'
+    CHECK (role IN ('admin', 'user', 'read-only'))
+);
+
+CREATE TABLE operations (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  inputs JSONB NOT NULL,
+  type TEXT NOT NULL
+    CHECK (type IN ('add', 'substract', 'multiply', 'divide', 'sum', 'evaluate')),
+  result TEXT NOT NULL,
+  mode TEXT NOT NULL DEFAULT 'float',
+  user_id BIGINT NOT NULL REFERENCES users (id),
+  expression TEXT,
+  request_id TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX operations_user_id ON operations (user_id, id);
+CREATE INDEX operations_request_id ON operations (request_id);
+
+CREATE TABLE refresh_tokens (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users (id),
+  token_hash TEXT NOT NULL UNIQUE,
+  family_id TEXT NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  revoked_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX refresh_tokens_family_id ON refresh_tokens (family_id);
+
+CREATE TABLE revoked_tokens (
+  jti TEXT PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users (id),
+  expires_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+CREATE TABLE api_keys (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users (id),
+  name TEXT NOT NULL,
+  prefix TEXT NOT NULL UNIQUE,
+  key_hash TEXT NOT NULL,
+  scopes JSONB NOT NULL DEFAULT '[]',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_used_at TIMESTAMPTZ,
+  revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX api_keys_user_id ON api_keys (user_id);
+
+-- +goose Down
+DROP TABLE api_keys;
+
+DROP TABLE revoked_tokens;
+
+DROP TABLE refresh_tokens;
+
+DROP TABLE operations;
+
+DROP TABLE users;
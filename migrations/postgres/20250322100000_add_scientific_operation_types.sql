@@ -0,0 +1,20 @@
+-- +goose Up
+-- The scientific functions are recorded with their own types.
+ALTER TABLE operations DROP CONSTRAINT operations_type_check;
+
+ALTER TABLE operations ADD CONSTRAINT operations_type_check
+  CHECK (type IN (
+    'add', 'substract', 'multiply', 'divide', 'sum', 'evaluate',
+    'pow', 'sqrt', 'root', 'exp', 'ln', 'log10', 'log',
+    'sin', 'cos', 'tan', 'asin', 'acos', 'atan',
+    'abs', 'floor', 'ceil', 'round'
+  ));
+
+-- +goose Down
+-- The operations of the scientific functions cannot be kept.
+DELETE FROM operations WHERE type NOT IN ('add', 'substract', 'multiply', 'divide', 'sum', 'evaluate');
+
+ALTER TABLE operations DROP CONSTRAINT operations_type_check;
+
+ALTER TABLE operations ADD CONSTRAINT operations_type_check
+  CHECK (type IN ('add', 'substract', 'multiply', 'divide', 'sum', 'evaluate'));
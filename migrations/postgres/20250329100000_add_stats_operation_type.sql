@@ -0,0 +1,27 @@
+-- +goose Up
+-- Statistics are recorded with their own type, their result is the JSON
+-- summary of the inputs.
+ALTER TABLE operations DROP CONSTRAINT operations_type_check;
+
+ALTER TABLE operations ADD CONSTRAINT operations_type_check
+  CHECK (type IN (
+    'add', 'substract', 'multiply', 'divide', 'sum', 'evaluate',
+    'pow', 'sqrt', 'root', 'exp', 'ln', 'log10', 'log',
+    'sin', 'cos', 'tan', 'asin', 'acos', 'atan',
+    'abs', 'floor', 'ceil', 'round',
+    'stats'
+  ));
+
+-- +goose Down
+-- The statistics cannot be kept.
+DELETE FROM operations WHERE type = 'stats';
+
+ALTER TABLE operations DROP CONSTRAINT operations_type_check;
+
+ALTER TABLE operations ADD CONSTRAINT operations_type_check
+  CHECK (type IN (
+    'add', 'substract', 'multiply', 'divide', 'sum', 'evaluate',
+    'pow', 'sqrt', 'root', 'exp', 'ln', 'log10', 'log',
+    'sin', 'cos', 'tan', 'asin', 'acos', 'atan',
+    'abs', 'floor', 'ceil', 'round'
+  ));
@@ -0,0 +1,35 @@
+-- +goose Up
+-- The vector and matrix operations are recorded with their own types, their
+-- operands and results are JSON text.
+ALTER TABLE operations DROP CONSTRAINT operations_type_check;
+
+ALTER TABLE operations ADD CONSTRAINT operations_type_check
+  CHECK (type IN (
+    'add', 'substract', 'multiply', 'divide', 'sum', 'evaluate',
+    'pow', 'sqrt', 'root', 'exp', 'ln', 'log10', 'log',
+    'sin', 'cos', 'tan', 'asin', 'acos', 'atan',
+    'abs', 'floor', 'ceil', 'round',
+    'stats',
+    'vector_dot', 'vector_cross', 'vector_norm',
+    'matrix_add', 'matrix_multiply', 'matrix_transpose',
+    'matrix_determinant', 'matrix_inverse', 'matrix_solve'
+  ));
+
+-- +goose Down
+-- The vector and matrix operations cannot be kept.
+DELETE FROM operations WHERE type IN (
+  'vector_dot', 'vector_cross', 'vector_norm',
+  'matrix_add', 'matrix_multiply', 'matrix_transpose',
+  'matrix_determinant', 'matrix_inverse', 'matrix_solve'
+);
+
+ALTER TABLE operations DROP CONSTRAINT operations_type_check;
+
+ALTER TABLE operations ADD CONSTRAINT operations_type_check
+  CHECK (type IN (
+    'add', 'substract', 'multiply', 'divide', 'sum', 'evaluate',
+    'pow', 'sqrt', 'root', 'exp', 'ln', 'log10', 'log',
+    'sin', 'cos', 'tan', 'asin', 'acos', 'atan',
+    'abs', 'floor', 'ceil', 'round',
+    'stats'
+  ));
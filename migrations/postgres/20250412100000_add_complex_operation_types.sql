@@ -0,0 +1,41 @@
+-- +goose Up
+-- The complex operations are recorded with their own types, their complex
+-- inputs and results in the a+bi form.
+ALTER TABLE operations DROP CONSTRAINT operations_type_check;
+
+ALTER TABLE operations ADD CONSTRAINT operations_type_check
+  CHECK (type IN (
+    'add', 'substract', 'multiply', 'divide', 'sum', 'evaluate',
+    'pow', 'sqrt', 'root', 'exp', 'ln', 'log10', 'log',
+    'sin', 'cos', 'tan', 'asin', 'acos', 'atan',
+    'abs', 'floor', 'ceil', 'round',
+    'stats',
+    'vector_dot', 'vector_cross', 'vector_norm',
+    'matrix_add', 'matrix_multiply', 'matrix_transpose',
+    'matrix_determinant', 'matrix_inverse', 'matrix_solve',
+    'complex_add', 'complex_substract', 'complex_multiply', 'complex_divide',
+    'complex_magnitude', 'complex_phase', 'complex_conjugate',
+    'complex_polar', 'complex_rectangular', 'complex_pow', 'complex_root'
+  ));
+
+-- +goose Down
+-- The complex operations cannot be kept.
+DELETE FROM operations WHERE type IN (
+  'complex_add', 'complex_substract', 'complex_multiply', 'complex_divide',
+  'complex_magnitude', 'complex_phase', 'complex_conjugate',
+  'complex_polar', 'complex_rectangular', 'complex_pow', 'complex_root'
+);
+
+ALTER TABLE operations DROP CONSTRAINT operations_type_check;
+
+ALTER TABLE operations ADD CONSTRAINT operations_type_check
+  CHECK (type IN (
+    'add', 'substract', 'multiply', 'divide', 'sum', 'evaluate',
+    'pow', 'sqrt', 'root', 'exp', 'ln', 'log10', 'log',
+    'sin', 'cos', 'tan', 'asin', 'acos', 'atan',
+    'abs', 'floor', 'ceil', 'round',
+    'stats',
+    'vector_dot', 'vector_cross', 'vector_norm',
+    'matrix_add', 'matrix_multiply', 'matrix_transpose',
+    'matrix_determinant', 'matrix_inverse', 'matrix_solve'
+  ));
@@ -0,0 +1,40 @@
+-- +goose Up
+-- SQLite cannot alter a CHECK constraint in place, so the table is rebuilt
+CREATE TABLE operations_new (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  inputs JSON NOT NULL,
+  type TEXT NOT NULL,
+  result REAL NOT NULL,
+  user_id INTEGER NOT NULL,
+  expression TEXT,
+  created_at TEXT NOT NULL DEFAULT (DATETIME('now', 'localtime')),
+  FOREIGN KEY (user_id) REFERENCES users (id),
+  CHECK (type IN ('add', 'substract', 'multiply', 'divide', 'sum', 'evaluate'))
+);
+
+INSERT INTO operations_new (id, inputs, type, result, user_id, created_at)
+  SELECT id, inputs, type, result, user_id, created_at FROM operations;
+
+DROP TABLE operations;
+
+ALTER TABLE operations_new RENAME TO operations;
+
+-- +goose Down
+CREATE TABLE operations_old (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  inputs JSON NOT NULL,
+  type TEXT NOT NULL,
+  result REAL NOT NULL,
+  user_id INTEGER NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (DATETIME('now', 'localtime')),
+  FOREIGN KEY (user_id) REFERENCES users (id),
+  CHECK (type IN ('add', 'substract', 'multiply', 'divide', 'sum'))
+);
+
+INSERT INTO operations_old (id, inputs, type, result, user_id, created_at)
+  SELECT id, inputs, type, result, user_id, created_at FROM operations
+  WHERE type != 'evaluate';
+
+DROP TABLE operations;
+
+ALTER TABLE operations_old RENAME TO operations;
@@ -0,0 +1,66 @@
+-- +goose Up
+-- Inputs and results are stored as decimal text so that precision mode
+-- operations keep their exact value. SQLite cannot change a column type in
+-- place, so the table is rebuilt. CAST(... AS TEXT) keeps 15 significant
+-- digits, so the numbers it would round are written with the 17 digits that
+-- give back the same float.
+CREATE TABLE operations_new (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  inputs JSON NOT NULL,
+  type TEXT NOT NULL,
+  result TEXT NOT NULL,
+  mode TEXT NOT NULL DEFAULT 'float',
+  user_id INTEGER NOT NULL,
+  expression TEXT,
+  created_at TEXT NOT NULL DEFAULT (DATETIME('now', 'localtime')),
+  FOREIGN KEY (user_id) REFERENCES users (id),
+  CHECK (type IN ('add', 'substract', 'multiply', 'divide', 'sum', 'evaluate'))
+);
+
+INSERT INTO operations_new (id, inputs, type, result, user_id, expression, created_at)
+  SELECT
+    id,
+    (
+      SELECT JSON_GROUP_ARRAY(
+        CASE WHEN CAST(CAST(value AS TEXT) AS REAL) = value THEN CAST(value AS TEXT) ELSE PRINTF('%!.17g', value) END
+      )
+      FROM JSON_EACH(operations.inputs)
+    ),
+    type,
+    CASE WHEN CAST(CAST(result AS TEXT) AS REAL) = result THEN CAST(result AS TEXT) ELSE PRINTF('%!.17g', result) END,
+    user_id,
+    expression,
+    created_at
+  FROM operations;
+
+DROP TABLE operations;
+
+ALTER TABLE operations_new RENAME TO operations;
+
+-- +goose Down
+CREATE TABLE operations_old (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  inputs JSON NOT NULL,
+  type TEXT NOT NULL,
+  result REAL NOT NULL,
+  user_id INTEGER NOT NULL,
+  expression TEXT,
+  created_at TEXT NOT NULL DEFAULT (DATETIME('now', 'localtime')),
+  FOREIGN KEY (user_id) REFERENCES users (id),
+  CHECK (type IN ('add', 'substract', 'multiply', 'divide', 'sum', 'evaluate'))
+);
+
+INSERT INTO operations_old (id, inputs, type, result, user_id, expression, created_at)
+  SELECT
+    id,
+    (SELECT JSON_GROUP_ARRAY(CAST(value AS REAL)) FROM JSON_EACH(operations.inputs)),
+    type,
+    CAST(result AS REAL),
+    user_id,
+    expression,
+    created_at
+  FROM operations;
+
+DROP TABLE operations;
+
+ALTER TABLE operations_old RENAME TO operations;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Users created before this migration have no password and cannot log in
+-- until one is set
+ALTER TABLE users ADD COLUMN password_hash TEXT;
+ALTER TABLE users ADD COLUMN failed_login_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN locked_until TEXT;
+
+CREATE UNIQUE INDEX users_pseudo_unique ON users (pseudo);
+
+-- +goose Down
+DROP INDEX users_pseudo_unique;
+
+ALTER TABLE users DROP COLUMN locked_until;
+ALTER TABLE users DROP COLUMN failed_login_attempts;
+ALTER TABLE users DROP COLUMN password_hash;
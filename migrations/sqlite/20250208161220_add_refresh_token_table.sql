@@ -0,0 +1,19 @@
+-- +goose Up
+-- Refresh tokens are opaque: only their SHA-256 hash is stored. Every token
+-- obtained by rotating another one shares its family_id, so that a reused
+-- token can revoke the whole chain.
+CREATE TABLE refresh_tokens (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  token_hash TEXT NOT NULL UNIQUE,
+  family_id TEXT NOT NULL,
+  expires_at TEXT NOT NULL,
+  revoked_at TEXT,
+  created_at TEXT NOT NULL DEFAULT (DATETIME('now', 'localtime')),
+  FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX refresh_tokens_family_id ON refresh_tokens (family_id);
+
+-- +goose Down
+DROP TABLE refresh_tokens;
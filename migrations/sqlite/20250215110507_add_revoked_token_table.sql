@@ -0,0 +1,22 @@
+-- +goose Up
+-- Access tokens revoked before their expiry, identified by their jti claim.
+-- Rows can be purged once expires_at is past since the token is rejected
+-- anyway by then.
+CREATE TABLE revoked_tokens (
+  jti TEXT PRIMARY KEY,
+  user_id INTEGER NOT NULL,
+  expires_at TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (DATETIME('now', 'localtime')),
+  FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+-- Tokens of a user issued before this date are rejected, which revokes all of
+-- their sessions at once
+ALTER TABLE users ADD COLUMN tokens_valid_after TEXT;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN tokens_valid_after;
+
+DROP TABLE revoked_tokens;
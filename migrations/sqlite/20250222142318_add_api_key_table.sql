@@ -0,0 +1,21 @@
+-- +goose Up
+-- Long-lived credentials for service-to-service callers. Only the SHA-256
+-- hash of a key is stored; its prefix is kept in clear to find it back and to
+-- let users tell their keys apart.
+CREATE TABLE api_keys (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  prefix TEXT NOT NULL UNIQUE,
+  key_hash TEXT NOT NULL,
+  scopes JSON NOT NULL DEFAULT '[]',
+  created_at TEXT NOT NULL DEFAULT (DATETIME('now', 'localtime')),
+  last_used_at TEXT,
+  revoked_at TEXT,
+  FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX api_keys_user_id ON api_keys (user_id);
+
+-- +goose Down
+DROP TABLE api_keys;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- ID of the request that recorded the operation, as returned in the
+-- X-Request-ID header and the error bodies. NULL for older operations.
+ALTER TABLE operations ADD COLUMN request_id TEXT;
+
+CREATE INDEX operations_request_id ON operations (request_id);
+
+-- +goose Down
+DROP INDEX operations_request_id;
+
+ALTER TABLE operations DROP COLUMN request_id;
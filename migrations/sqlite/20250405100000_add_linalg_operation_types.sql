@@ -0,0 +1,73 @@
+-- +goose Up
+-- The vector and matrix operations are recorded with their own types, their
+-- operands and results are JSON text. SQLite cannot alter a CHECK
+-- constraint, so the table is rebuilt.
+CREATE TABLE operations_new (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  inputs JSON NOT NULL,
+  type TEXT NOT NULL,
+  result TEXT NOT NULL,
+  mode TEXT NOT NULL DEFAULT 'float',
+  user_id INTEGER NOT NULL,
+  expression TEXT,
+  created_at TEXT NOT NULL DEFAULT (DATETIME('now', 'localtime')),
+  request_id TEXT,
+  FOREIGN KEY (user_id) REFERENCES users (id),
+  CHECK (type IN (
+    'add', 'substract', 'multiply', 'divide', 'sum', 'evaluate',
+    'pow', 'sqrt', 'root', 'exp', 'ln', 'log10', 'log',
+    'sin', 'cos', 'tan', 'asin', 'acos', 'atan',
+    'abs', 'floor', 'ceil', 'round',
+    'stats',
+    'vector_dot', 'vector_cross', 'vector_norm',
+    'matrix_add', 'matrix_multiply', 'matrix_transpose',
+    'matrix_determinant', 'matrix_inverse', 'matrix_solve'
+  ))
+);
+
+INSERT INTO operations_new (id, inputs, type, result, mode, user_id, expression, created_at, request_id)
+  SELECT id, inputs, type, result, mode, user_id, expression, created_at, request_id
+  FROM operations;
+
+DROP TABLE operations;
+
+ALTER TABLE operations_new RENAME TO operations;
+
+CREATE INDEX operations_request_id ON operations (request_id);
+
+-- +goose Down
+-- The vector and matrix operations cannot be kept.
+CREATE TABLE operations_old (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  inputs JSON NOT NULL,
+  type TEXT NOT NULL,
+  result TEXT NOT NULL,
+  mode TEXT NOT NULL DEFAULT 'float',
+  user_id INTEGER NOT NULL,
+  expression TEXT,
+  created_at TEXT NOT NULL DEFAULT (DATETIME('now', 'localtime')),
+  request_id TEXT,
+  FOREIGN KEY (user_id) REFERENCES users (id),
+  CHECK (type IN (
+    'add', 'substract', 'multiply', 'divide', 'sum', 'evaluate',
+    'pow', 'sqrt', 'root', 'exp', 'ln', 'log10', 'log',
+    'sin', 'cos', 'tan', 'asin', 'acos', 'atan',
+    'abs', 'floor', 'ceil', 'round',
+    'stats'
+  ))
+);
+
+INSERT INTO operations_old (id, inputs, type, result, mode, user_id, expression, created_at, request_id)
+  SELECT id, inputs, type, result, mode, user_id, expression, created_at, request_id
+  FROM operations
+  WHERE type NOT IN (
+    'vector_dot', 'vector_cross', 'vector_norm',
+    'matrix_add', 'matrix_multiply', 'matrix_transpose',
+    'matrix_determinant', 'matrix_inverse', 'matrix_solve'
+  );
+
+DROP TABLE operations;
+
+ALTER TABLE operations_old RENAME TO operations;
+
+CREATE INDEX operations_request_id ON operations (request_id);
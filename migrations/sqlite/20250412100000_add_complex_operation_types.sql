@@ -0,0 +1,79 @@
+-- +goose Up
+-- The complex operations are recorded with their own types, their complex
+-- inputs and results in the a+bi form. SQLite cannot alter a CHECK
+-- constraint, so the table is rebuilt.
+CREATE TABLE operations_new (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  inputs JSON NOT NULL,
+  type TEXT NOT NULL,
+  result TEXT NOT NULL,
+  mode TEXT NOT NULL DEFAULT 'float',
+  user_id INTEGER NOT NULL,
+  expression TEXT,
+  created_at TEXT NOT NULL DEFAULT (DATETIME('now', 'localtime')),
+  request_id TEXT,
+  FOREIGN KEY (user_id) REFERENCES users (id),
+  CHECK (type IN (
+    'add', 'substract', 'multiply', 'divide', 'sum', 'evaluate',
+    'pow', 'sqrt', 'root', 'exp', 'ln', 'log10', 'log',
+    'sin', 'cos', 'tan', 'asin', 'acos', 'atan',
+    'abs', 'floor', 'ceil', 'round',
+    'stats',
+    'vector_dot', 'vector_cross', 'vector_norm',
+    'matrix_add', 'matrix_multiply', 'matrix_transpose',
+    'matrix_determinant', 'matrix_inverse', 'matrix_solve',
+    'complex_add', 'complex_substract', 'complex_multiply', 'complex_divide',
+    'complex_magnitude', 'complex_phase', 'complex_conjugate',
+    'complex_polar', 'complex_rectangular', 'complex_pow', 'complex_root'
+  ))
+);
+
+INSERT INTO operations_new (id, inputs, type, result, mode, user_id, expression, created_at, request_id)
+  SELECT id, inputs, type, result, mode, user_id, expression, created_at, request_id
+  FROM operations;
+
+DROP TABLE operations;
+
+ALTER TABLE operations_new RENAME TO operations;
+
+CREATE INDEX operations_request_id ON operations (request_id);
+
+-- +goose Down
+-- The complex operations cannot be kept.
+CREATE TABLE operations_old (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  inputs JSON NOT NULL,
+  type TEXT NOT NULL,
+  result TEXT NOT NULL,
+  mode TEXT NOT NULL DEFAULT 'float',
+  user_id INTEGER NOT NULL,
+  expression TEXT,
+  created_at TEXT NOT NULL DEFAULT (DATETIME('now', 'localtime')),
+  request_id TEXT,
+  FOREIGN KEY (user_id) REFERENCES users (id),
+  CHECK (type IN (
+    'add', 'substract', 'multiply', 'divide', 'sum', 'evaluate',
+    'pow', 'sqrt', 'root', 'exp', 'ln', 'log10', 'log',
+    'sin', 'cos', 'tan', 'asin', 'acos', 'atan',
+    'abs', 'floor', 'ceil', 'round',
+    'stats',
+    'vector_dot', 'vector_cross', 'vector_norm',
+    'matrix_add', 'matrix_multiply', 'matrix_transpose',
+    'matrix_determinant', 'matrix_inverse', 'matrix_solve'
+  ))
+);
+
+INSERT INTO operations_old (id, inputs, type, result, mode, user_id, expression, created_at, request_id)
+  SELECT id, inputs, type, result, mode, user_id, expression, created_at, request_id
+  FROM operations
+  WHERE type NOT IN (
+    'complex_add', 'complex_substract', 'complex_multiply', 'complex_divide',
+    'complex_magnitude', 'complex_phase', 'complex_conjugate',
+    'complex_polar', 'complex_rectangular', 'complex_pow', 'complex_root'
+  );
+
+DROP TABLE operations;
+
+ALTER TABLE operations_old RENAME TO operations;
+
+CREATE INDEX operations_request_id ON operations (request_id);
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minPasswordLength = 10
+	// bcrypt ignores everything after the 72nd byte
+	maxPasswordLength = 72
+
+	maxLoginAttempts = 5
+	lockoutDuration  = 15 * time.Minute
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid pseudo or password")
+	ErrAccountLocked      = errors.New("too many failed login attempts, account temporarily locked")
+	ErrInvalidPseudo      = errors.New("pseudo should be 3 to 32 letters, digits, '_' or '-'")
+	ErrPasswordLength     = fmt.Errorf("password should be between %d and %d bytes long", minPasswordLength, maxPasswordLength)
+	ErrPasswordWeak       = errors.New("password should contain at least a letter and a digit")
+	ErrPasswordPseudo     = errors.New("password should not contain the pseudo")
+)
+
+var pseudoPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{3,32}$`)
+
+// dummyPasswordHash is compared against when the user does not exist or has
+// no password, so that the response time does not reveal it.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("not-a-real-password-0"), bcrypt.DefaultCost)
+	return hash
+})
+
+func validatePseudo(pseudo string) error {
+	if !pseudoPattern.MatchString(pseudo) {
+		return ErrInvalidPseudo
+	}
+
+	return nil
+}
+
+func validatePassword(pseudo, password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return ErrPasswordLength
+	}
+
+	var hasLetter, hasDigit bool
+	for _, c := range password {
+		hasLetter = hasLetter || unicode.IsLetter(c)
+		hasDigit = hasDigit || unicode.IsDigit(c)
+	}
+	if !hasLetter || !hasDigit {
+		return ErrPasswordWeak
+	}
+
+	if strings.Contains(strings.ToLower(password), strings.ToLower(pseudo)) {
+		return ErrPasswordPseudo
+	}
+
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// checkPassword compares password with hash in constant time. An empty hash
+// never matches but still costs a full comparison.
+func checkPassword(hash, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
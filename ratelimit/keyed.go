@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// KeyedLimiter maintains one TokenBucket per key (an authenticated user, a
+// client IP...) so that a noisy caller only exhausts its own bucket. Buckets
+// that have not been used for idleTTL are evicted to keep memory bounded.
+type KeyedLimiter struct {
+	count   int64
+	rate    int64
+	idleTTL time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*keyedBucket
+
+	running atomic.Bool
+}
+
+type keyedBucket struct {
+	bucket   *TokenBucket
+	lastSeen time.Time
+}
+
+func NewKeyedLimiter(count, rate int64, idleTTL time.Duration) *KeyedLimiter {
+	return &KeyedLimiter{
+		count:   count,
+		rate:    rate,
+		idleTTL: idleTTL,
+		buckets: make(map[string]*keyedBucket),
+	}
+}
+
+// Start runs the eviction loop until ctx is cancelled. It is the only
+// goroutine of the limiter, the buckets refill themselves when used.
+func (kl *KeyedLimiter) Start(ctx context.Context) {
+	kl.running.Store(true)
+	go func() {
+		defer kl.running.Store(false)
+
+		ticker := time.NewTicker(kl.idleTTL / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				kl.evict(time.Now())
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Bucket returns the bucket of key, creating it on first use.
+func (kl *KeyedLimiter) Bucket(key string) *TokenBucket {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+
+	kb, ok := kl.buckets[key]
+	if !ok {
+		kb = &keyedBucket{bucket: NewTokenBucket(kl.count, kl.rate)}
+		kl.buckets[key] = kb
+	}
+	kb.lastSeen = time.Now()
+
+	return kb.bucket
+}
+
+// Running reports whether the eviction loop started by Start is running.
+func (kl *KeyedLimiter) Running() bool {
+	return kl.running.Load()
+}
+
+// Capacity is the number of tokens of a full bucket.
+func (kl *KeyedLimiter) Capacity() int64 {
+	return kl.count
+}
+
+// Len returns the number of buckets currently tracked.
+func (kl *KeyedLimiter) Len() int {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+
+	return len(kl.buckets)
+}
+
+// Levels returns the number of tokens available in each tracked bucket.
+func (kl *KeyedLimiter) Levels() []int64 {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+
+	levels := make([]int64, 0, len(kl.buckets))
+	for _, kb := range kl.buckets {
+		levels = append(levels, kb.bucket.Available())
+	}
+
+	return levels
+}
+
+// evict drops the buckets idle since idleTTL. Such a bucket has had time to
+// refill, so recreating it later gives the caller the same allowance.
+func (kl *KeyedLimiter) evict(now time.Time) {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+
+	for key, kb := range kl.buckets {
+		if now.Sub(kb.lastSeen) >= kl.idleTTL {
+			delete(kl.buckets, key)
+		}
+	}
+}
@@ -1,51 +1,117 @@
 package ratelimit
 
 import (
-	"context"
+	"sync"
 	"time"
 )
 
-type tokens chan struct{}
-
+// TokenBucket holds up to count tokens and gains one every interval. The
+// tokens are added lazily, from the time elapsed since the last refill, when
+// the bucket is used, so that a bucket needs no goroutine of its own.
 type TokenBucket struct {
-	Tokens tokens
-	count  int64
-	ticker *time.Ticker
+	count    int64
+	interval time.Duration
+	// now returns the current time, replaced in the tests
+	now func() time.Time
+
+	mu     sync.Mutex
+	tokens int64
+	// lastRefill is the time the last token was added, or the time the
+	// bucket was last found full
+	lastRefill time.Time
+}
+
+// Reservation is the outcome of TryConsume.
+type Reservation struct {
+	Allowed bool
+	// Remaining is the number of tokens left after this call
+	Remaining int64
+	// RetryAfter is the time until the next token is added to the bucket
+	RetryAfter time.Duration
+	// Reset is when the bucket will be full again
+	Reset time.Time
 }
 
 func NewTokenBucket(count, rate int64) *TokenBucket {
-	tokens := make(chan struct{}, count)
+	everyMs := 1 / float64(rate) * 1000
+	return &TokenBucket{
+		count:      count,
+		interval:   time.Duration(int64(everyMs) * int64(time.Millisecond)),
+		now:        time.Now,
+		tokens:     count,
+		lastRefill: time.Now(),
+	}
+}
 
-	c := int(count)
-	for i := 0; i < c; i++ {
-		tokens <- struct{}{}
+// refill adds the tokens earned since lastRefill. The caller holds tb.mu.
+func (tb *TokenBucket) refill(now time.Time) {
+	if tb.tokens >= tb.count {
+		// A full bucket earns nothing, the next token is counted from the
+		// time it is drawn from
+		tb.lastRefill = now
+		return
 	}
 
-	everyMs := 1 / float64(rate) * 1000
-	return &TokenBucket{
-		Tokens: tokens,
-		count:  count,
-		ticker: time.NewTicker(time.Duration(int64(everyMs) * int64(time.Millisecond))),
+	earned := int64(now.Sub(tb.lastRefill) / tb.interval)
+	if earned <= 0 {
+		return
+	}
+
+	tb.tokens = min(tb.tokens+earned, tb.count)
+	if tb.tokens == tb.count {
+		tb.lastRefill = now
+	} else {
+		tb.lastRefill = tb.lastRefill.Add(time.Duration(earned) * tb.interval)
 	}
 }
 
-func (tb *TokenBucket) Start(ctx context.Context) {
-	go func() {
-		for {
-			select {
-			case <-tb.ticker.C:
-				select {
-				case tb.Tokens <- struct{}{}:
-				default:
-				}
-			case <-ctx.Done():
-				// The context was likely cancelled
-				return
-			}
+// Consume takes a token, blocking until one is available.
+func (tb *TokenBucket) Consume() {
+	for {
+		res := tb.TryConsume()
+		if res.Allowed {
+			return
 		}
-	}()
+		time.Sleep(res.RetryAfter)
+	}
 }
 
-func (tb *TokenBucket) Consume() {
-	<-tb.Tokens
+// TryConsume takes a token if one is available and returns immediately
+// either way, along with the state of the bucket after the call.
+func (tb *TokenBucket) TryConsume() Reservation {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := tb.now()
+	tb.refill(now)
+
+	var res Reservation
+	if tb.tokens > 0 {
+		tb.tokens--
+		res.Allowed = true
+	}
+
+	res.Remaining = tb.tokens
+	res.Reset = now
+	if missing := tb.count - tb.tokens; missing > 0 {
+		next := tb.lastRefill.Add(tb.interval)
+		res.RetryAfter = next.Sub(now)
+		res.Reset = next.Add(time.Duration(missing-1) * tb.interval)
+	}
+
+	return res
+}
+
+// Capacity is the number of tokens of a full bucket.
+func (tb *TokenBucket) Capacity() int64 {
+	return tb.count
+}
+
+// Available is the number of tokens currently in the bucket.
+func (tb *TokenBucket) Available() int64 {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.refill(tb.now())
+	return tb.tokens
 }
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTryConsume(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+
+	// 3 tokens, one added every 500ms
+	tb := NewTokenBucket(3, 2)
+	tb.now = func() time.Time { return now }
+	tb.lastRefill = start
+
+	tests := []struct {
+		elapsed    time.Duration
+		allowed    bool
+		remaining  int64
+		retryAfter time.Duration
+		reset      time.Duration
+	}{
+		{0, true, 2, 500 * time.Millisecond, 500 * time.Millisecond},
+		{0, true, 1, 500 * time.Millisecond, 1000 * time.Millisecond},
+		{100 * time.Millisecond, true, 0, 400 * time.Millisecond, 1500 * time.Millisecond},
+		{200 * time.Millisecond, false, 0, 300 * time.Millisecond, 1500 * time.Millisecond},
+		// One token was added at 500ms, the next one is due at 1s
+		{600 * time.Millisecond, true, 0, 400 * time.Millisecond, 2000 * time.Millisecond},
+		// Two more tokens at 1s and 1.5s, the third one is due at 2s
+		{1700 * time.Millisecond, true, 1, 300 * time.Millisecond, 2500 * time.Millisecond},
+		// The bucket filled up at 2.5s and stays full, the next token is
+		// counted from the time this one is taken
+		{10 * time.Second, true, 2, 500 * time.Millisecond, 10500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		now = start.Add(tt.elapsed)
+		res := tb.TryConsume()
+
+		if res.Allowed != tt.allowed || res.Remaining != tt.remaining {
+			t.Errorf("at %v: got allowed %v and %d remaining, expected %v and %d", tt.elapsed, res.Allowed, res.Remaining, tt.allowed, tt.remaining)
+		}
+		if res.RetryAfter != tt.retryAfter {
+			t.Errorf("at %v: got retry after %v, expected %v", tt.elapsed, res.RetryAfter, tt.retryAfter)
+		}
+		if expected := start.Add(tt.reset); !res.Reset.Equal(expected) {
+			t.Errorf("at %v: got reset at %v, expected %v", tt.elapsed, res.Reset.Sub(start), tt.reset)
+		}
+	}
+
+	if got := tb.Available(); got != 2 {
+		t.Errorf("Available() = %d, expected 2", got)
+	}
+}
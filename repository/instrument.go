@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/NDOY3M4N/api-calculator/metrics"
+)
+
+var tracer = otel.Tracer("github.com/NDOY3M4N/api-calculator/repository")
+
+// Instrument starts the span of the store method query, run on the database
+// system, and bounds it with timeout unless it is 0. The returned function
+// ends the span, cancels the context and records the latency of the method,
+// it is meant to be deferred.
+func Instrument(ctx context.Context, system attribute.KeyValue, query string, timeout time.Duration) (context.Context, func()) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "Repository."+query,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(system, semconv.DBOperationName(query)),
+	)
+
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	return ctx, func() {
+		cancel()
+		span.End()
+		metrics.ObserveQuery(query, start)
+	}
+}
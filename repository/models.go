@@ -2,9 +2,23 @@ package repository
 
 import "time"
 
+// Role tells which scopes a user is granted.
+type Role string
+
+const (
+	RoleAdmin    Role = "admin"
+	RoleUser     Role = "user"
+	RoleReadOnly Role = "read-only"
+)
+
 type User struct {
-	Id     int64  `json:"id"`
-	Pseudo string `json:"pseudo"`
+	Id                  int64      `json:"id"`
+	Pseudo              string     `json:"pseudo"`
+	Role                Role       `json:"role"`
+	PasswordHash        string     `json:"-"`
+	FailedLoginAttempts int        `json:"-"`
+	LockedUntil         *time.Time `json:"-"`
+	TokensValidAfter    *time.Time `json:"-"`
 }
 
 type OperationType string
@@ -15,13 +29,106 @@ const (
 	TypeMultiply  OperationType = "multiply"
 	TypeDivide    OperationType = "divide"
 	TypeSum       OperationType = "sum"
+	TypeEvaluate  OperationType = "evaluate"
+
+	// Scientific functions, computed on float64
+	TypePow   OperationType = "pow"
+	TypeSqrt  OperationType = "sqrt"
+	TypeRoot  OperationType = "root"
+	TypeExp   OperationType = "exp"
+	TypeLn    OperationType = "ln"
+	TypeLog10 OperationType = "log10"
+	TypeLog   OperationType = "log"
+	TypeSin   OperationType = "sin"
+	TypeCos   OperationType = "cos"
+	TypeTan   OperationType = "tan"
+	TypeAsin  OperationType = "asin"
+	TypeAcos  OperationType = "acos"
+	TypeAtan  OperationType = "atan"
+	TypeAbs   OperationType = "abs"
+	TypeFloor OperationType = "floor"
+	TypeCeil  OperationType = "ceil"
+	TypeRound OperationType = "round"
+
+	// TypeStats results are the JSON summary of the inputs
+	TypeStats OperationType = "stats"
+
+	// Vector and matrix operations, their operands and the vectors and
+	// matrices they return are stored as JSON text
+	TypeVectorDot         OperationType = "vector_dot"
+	TypeVectorCross       OperationType = "vector_cross"
+	TypeVectorNorm        OperationType = "vector_norm"
+	TypeMatrixAdd         OperationType = "matrix_add"
+	TypeMatrixMultiply    OperationType = "matrix_multiply"
+	TypeMatrixTranspose   OperationType = "matrix_transpose"
+	TypeMatrixDeterminant OperationType = "matrix_determinant"
+	TypeMatrixInverse     OperationType = "matrix_inverse"
+	TypeMatrixSolve       OperationType = "matrix_solve"
+
+	// Complex operations, their complex inputs and results are stored in the
+	// a+bi form, e.g. "3-4i"
+	TypeComplexAdd         OperationType = "complex_add"
+	TypeComplexSubstract   OperationType = "complex_substract"
+	TypeComplexMultiply    OperationType = "complex_multiply"
+	TypeComplexDivide      OperationType = "complex_divide"
+	TypeComplexMagnitude   OperationType = "complex_magnitude"
+	TypeComplexPhase       OperationType = "complex_phase"
+	TypeComplexConjugate   OperationType = "complex_conjugate"
+	TypeComplexPolar       OperationType = "complex_polar"
+	TypeComplexRectangular OperationType = "complex_rectangular"
+	TypeComplexPow         OperationType = "complex_pow"
+	TypeComplexRoot        OperationType = "complex_root"
+)
+
+// OperationMode tells how the inputs and result of an operation were computed.
+type OperationMode string
+
+const (
+	// ModeFloat operations are computed on float64
+	ModeFloat OperationMode = "float"
+	// ModeDecimal operations are computed exactly with math/big
+	ModeDecimal OperationMode = "decimal"
+	// ModeFraction operations are computed exactly with math/big, their
+	// inputs and result are stored as reduced fractions such as "1/3"
+	ModeFraction OperationMode = "fraction"
 )
 
 type Operations struct {
-	Id        int64         `json:"id"`
-	Inputs    []float64     `json:"inputs"`
-	Type      OperationType `json:"type"`
-	Result    float64       `json:"results"`
-	UserId    float64       `json:"user_id"`
-	CreatedAt time.Time     `json:"created_at"`
+	Id         int64         `json:"id"`
+	Inputs     []string      `json:"inputs"`
+	Type       OperationType `json:"type"`
+	Result     string        `json:"results"`
+	Mode       OperationMode `json:"mode"`
+	UserId     int64         `json:"user_id"`
+	Expression string        `json:"expression,omitempty"`
+	RequestId  string        `json:"request_id,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+type RefreshToken struct {
+	Id        int64
+	UserId    int64
+	TokenHash string
+	FamilyId  string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+type RevokedToken struct {
+	Jti       string
+	UserId    int64
+	ExpiresAt time.Time
+}
+
+type APIKey struct {
+	Id         int64      `json:"id"`
+	UserId     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
 }
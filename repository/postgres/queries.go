@@ -0,0 +1,374 @@
+// Package postgres stores the data of the API in a PostgreSQL database,
+// through the pgx driver of database/sql.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/NDOY3M4N/api-calculator/metrics"
+	"github.com/NDOY3M4N/api-calculator/repository"
+)
+
+// uniqueViolation is the SQLSTATE of a unique constraint violation.
+const uniqueViolation = "23505"
+
+const userColumns = "id, pseudo, role, password_hash, failed_login_attempts, locked_until, tokens_valid_after"
+
+type Store struct {
+	db *sql.DB
+	// queryTimeout bounds each method, 0 disables it
+	queryTimeout time.Duration
+}
+
+var _ repository.Store = (*Store)(nil)
+
+// New returns the store of db, whose methods fail with
+// context.DeadlineExceeded after queryTimeout.
+func New(db *sql.DB, queryTimeout time.Duration) *Store {
+	return &Store{db: db, queryTimeout: queryTimeout}
+}
+
+func (s *Store) instrument(ctx context.Context, query string) (context.Context, func()) {
+	return repository.Instrument(ctx, semconv.DBSystemPostgreSQL, query, s.queryTimeout)
+}
+
+// Ping checks that the database is reachable.
+func (s *Store) Ping(ctx context.Context) error {
+	ctx, done := s.instrument(ctx, "Ping")
+	defer done()
+
+	return s.db.PingContext(ctx)
+}
+
+// SchemaVersion returns the version of the last applied migration.
+func (s *Store) SchemaVersion(ctx context.Context) (int64, error) {
+	ctx, done := s.instrument(ctx, "SchemaVersion")
+	defer done()
+
+	var version sql.NullInt64
+	err := s.db.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_version").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return version.Int64, nil
+}
+
+func (s *Store) FindUserById(ctx context.Context, id int) (*repository.User, error) {
+	ctx, done := s.instrument(ctx, "FindUserById")
+	defer done()
+
+	row := s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", id)
+
+	return s.find(row)
+}
+
+func (s *Store) FindUserByPseudo(ctx context.Context, pseudo string) (*repository.User, error) {
+	ctx, done := s.instrument(ctx, "FindUserByPseudo")
+	defer done()
+
+	row := s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE pseudo = $1", pseudo)
+
+	return s.find(row)
+}
+
+func (s *Store) find(row *sql.Row) (*repository.User, error) {
+	user, err := scanUser(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *Store) ListUsers(ctx context.Context) ([]repository.User, error) {
+	ctx, done := s.instrument(ctx, "ListUsers")
+	defer done()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []repository.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	return users, rows.Err()
+}
+
+func (s *Store) UpdateUserRole(ctx context.Context, id int, role repository.Role) error {
+	ctx, done := s.instrument(ctx, "UpdateUserRole")
+	defer done()
+
+	res, err := s.db.ExecContext(ctx, "UPDATE users SET role = $1 WHERE id = $2", string(role), id)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func scanUser(row scanner) (*repository.User, error) {
+	var (
+		user         = new(repository.User)
+		passwordHash sql.NullString
+		lockedUntil  sql.NullTime
+		validAfter   sql.NullTime
+	)
+
+	err := row.Scan(&user.Id, &user.Pseudo, &user.Role, &passwordHash, &user.FailedLoginAttempts, &lockedUntil, &validAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	user.PasswordHash = passwordHash.String
+	user.LockedUntil = nullTime(lockedUntil)
+	user.TokensValidAfter = nullTime(validAfter)
+
+	return user, nil
+}
+
+func (s *Store) CreateUser(ctx context.Context, pseudo, passwordHash string) (*repository.User, error) {
+	ctx, done := s.instrument(ctx, "CreateUser")
+	defer done()
+
+	var id int64
+	err := s.db.QueryRowContext(ctx,
+		"INSERT INTO users (pseudo, password_hash) VALUES ($1, $2) RETURNING id",
+		pseudo, passwordHash,
+	).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, repository.ErrPseudoTaken
+		}
+		return nil, err
+	}
+
+	return &repository.User{Id: id, Pseudo: pseudo, Role: repository.RoleUser, PasswordHash: passwordHash}, nil
+}
+
+// RecordLoginFailure counts a failed login attempt. Once maxAttempts is
+// reached the user is locked until now+lockout and the counter starts over.
+func (s *Store) RecordLoginFailure(ctx context.Context, userID int64, maxAttempts int, lockout time.Duration) error {
+	ctx, done := s.instrument(ctx, "RecordLoginFailure")
+	defer done()
+
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE users SET
+			failed_login_attempts = CASE WHEN failed_login_attempts + 1 >= $1 THEN 0 ELSE failed_login_attempts + 1 END,
+			locked_until = CASE WHEN failed_login_attempts + 1 >= $1 THEN CAST($2 AS TIMESTAMPTZ) ELSE locked_until END
+		WHERE id = $3`,
+		maxAttempts, time.Now().Add(lockout), userID,
+	)
+
+	return err
+}
+
+// ResetLoginFailures clears the failed attempts and the lock of a user after
+// a successful login.
+func (s *Store) ResetLoginFailures(ctx context.Context, userID int64) error {
+	ctx, done := s.instrument(ctx, "ResetLoginFailures")
+	defer done()
+
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE users SET failed_login_attempts = 0, locked_until = NULL WHERE id = $1",
+		userID,
+	)
+
+	return err
+}
+
+func (s *Store) AddOperation(ctx context.Context, param repository.AddOperationParams) error {
+	ctx, done := s.instrument(ctx, "AddOperation")
+	defer done()
+
+	inputs, err := json.Marshal(param.Inputs)
+	if err != nil {
+		return err
+	}
+	if param.Mode == "" {
+		param.Mode = repository.ModeFloat
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		"INSERT INTO operations (inputs, type, result, mode, user_id, expression, request_id) VALUES (CAST($1 AS JSONB), $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''))",
+		string(inputs), string(param.Type), param.Result, string(param.Mode), param.UserId, param.Expression, param.RequestId,
+	)
+	if err != nil {
+		return err
+	}
+
+	metrics.OperationsRecorded.WithLabelValues(string(param.Type), string(param.Mode)).Inc()
+
+	return nil
+}
+
+// numericResult is the result of an operation as a number, NULL for the
+// JSON results of the statistics, vectors and matrices and for the complex
+// numbers, which the result filters skip. The fractions of the fraction mode
+// are divided, CAST would reject them.
+const numericResult = "(CASE" +
+	" WHEN result ~ 'i$' THEN NULL" +
+	" WHEN result ~ '^-?[0-9]+/[0-9]+$' THEN CAST(split_part(result, '/', 1) AS DOUBLE PRECISION) / CAST(split_part(result, '/', 2) AS DOUBLE PRECISION)" +
+	" WHEN result ~ '^-?[0-9]' THEN CAST(result AS DOUBLE PRECISION)" +
+	" END)"
+
+const operationColumns = "id, inputs, type, result, mode, user_id, expression, request_id, created_at"
+
+func (s *Store) ListOperations(ctx context.Context, params repository.ListOperationsParams) ([]repository.Operations, error) {
+	ctx, done := s.instrument(ctx, "ListOperations")
+	defer done()
+
+	var (
+		where []string
+		args  []any
+	)
+	// arg adds a parameter to the query and returns its placeholder
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	where = append(where, "user_id = "+arg(params.UserId))
+	if params.Type != "" {
+		where = append(where, "type = "+arg(string(params.Type)))
+	}
+	if params.RequestId != "" {
+		where = append(where, "request_id = "+arg(params.RequestId))
+	}
+	if !params.From.IsZero() {
+		where = append(where, "created_at >= "+arg(params.From))
+	}
+	if !params.To.IsZero() {
+		where = append(where, "created_at <= "+arg(params.To))
+	}
+	if params.MinResult != nil {
+		where = append(where, numericResult+" >= "+arg(*params.MinResult))
+	}
+	if params.MaxResult != nil {
+		where = append(where, numericResult+" <= "+arg(*params.MaxResult))
+	}
+
+	order := "DESC"
+	if params.Order == repository.SortAsc {
+		order = "ASC"
+	}
+
+	if params.AfterId > 0 {
+		if order == "ASC" {
+			where = append(where, "id > "+arg(params.AfterId))
+		} else {
+			where = append(where, "id < "+arg(params.AfterId))
+		}
+	}
+
+	query := "SELECT " + operationColumns + " FROM operations WHERE " + strings.Join(where, " AND ") + " ORDER BY id " + order
+	if params.Limit > 0 {
+		query += " LIMIT " + arg(params.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	operations := []repository.Operations{}
+	for rows.Next() {
+		operation, err := scanOperation(rows)
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, *operation)
+	}
+
+	return operations, rows.Err()
+}
+
+func (s *Store) FindOperationById(ctx context.Context, userID int, id int64) (*repository.Operations, error) {
+	ctx, done := s.instrument(ctx, "FindOperationById")
+	defer done()
+
+	row := s.db.QueryRowContext(ctx, "SELECT "+operationColumns+" FROM operations WHERE id = $1 AND user_id = $2", id, userID)
+
+	operation, err := scanOperation(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrOperationNotFound
+		}
+		return nil, err
+	}
+
+	return operation, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOperation(row scanner) (*repository.Operations, error) {
+	var (
+		operation  repository.Operations
+		inputs     []byte
+		expression sql.NullString
+		requestID  sql.NullString
+	)
+
+	err := row.Scan(
+		&operation.Id,
+		&inputs,
+		&operation.Type,
+		&operation.Result,
+		&operation.Mode,
+		&operation.UserId,
+		&expression,
+		&requestID,
+		&operation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(inputs, &operation.Inputs); err != nil {
+		return nil, err
+	}
+
+	operation.Expression = expression.String
+	operation.RequestId = requestID.String
+
+	return &operation, nil
+}
+
+func nullTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+
+	return &value.Time
+}
@@ -0,0 +1,38 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/NDOY3M4N/api-calculator/migrations"
+	"github.com/NDOY3M4N/api-calculator/repository"
+	"github.com/NDOY3M4N/api-calculator/repository/postgres"
+	"github.com/NDOY3M4N/api-calculator/repository/storetest"
+)
+
+// TestStore runs against the database of STORETEST_POSTGRES_DSN, which should
+// be a disposable one: the suite migrates it and leaves its rows behind.
+func TestStore(t *testing.T) {
+	dsn := os.Getenv("STORETEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("STORETEST_POSTGRES_DSN is not set")
+	}
+
+	storetest.Run(t, func(t *testing.T) repository.Store {
+		db, err := sql.Open("pgx", dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		if _, err := migrations.New(db, migrations.Postgres).Up(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		return postgres.New(db, 0)
+	})
+}
@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/NDOY3M4N/api-calculator/repository"
+)
+
+func (s *Store) CreateRefreshToken(ctx context.Context, param repository.CreateRefreshTokenParams) error {
+	ctx, done := s.instrument(ctx, "CreateRefreshToken")
+	defer done()
+
+	return createRefreshToken(ctx, s.db, param)
+}
+
+func (s *Store) FindRefreshToken(ctx context.Context, tokenHash string) (*repository.RefreshToken, error) {
+	ctx, done := s.instrument(ctx, "FindRefreshToken")
+	defer done()
+
+	row := s.db.QueryRowContext(ctx,
+		"SELECT id, user_id, token_hash, family_id, expires_at, revoked_at, created_at FROM refresh_tokens WHERE token_hash = $1",
+		tokenHash,
+	)
+
+	var (
+		token     repository.RefreshToken
+		revokedAt sql.NullTime
+	)
+
+	err := row.Scan(&token.Id, &token.UserId, &token.TokenHash, &token.FamilyId, &token.ExpiresAt, &revokedAt, &token.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+
+	token.RevokedAt = nullTime(revokedAt)
+
+	return &token, nil
+}
+
+// RotateRefreshToken revokes the token oldID and stores next in its place.
+// It fails with ErrRefreshTokenUsed when oldID was already revoked: the
+// UPDATE of a concurrent rotation waits for this one and then matches no row.
+func (s *Store) RotateRefreshToken(ctx context.Context, oldID int64, next repository.CreateRefreshTokenParams) error {
+	ctx, done := s.instrument(ctx, "RotateRefreshToken")
+	defer done()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL",
+		time.Now(), oldID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrRefreshTokenUsed
+	}
+
+	if err := createRefreshToken(ctx, tx, next); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeRefreshTokenFamily revokes every token obtained from the same login.
+func (s *Store) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	ctx, done := s.instrument(ctx, "RevokeRefreshTokenFamily")
+	defer done()
+
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL",
+		time.Now(), familyID,
+	)
+
+	return err
+}
+
+// RevokeToken adds the access token jti to the denylist until it expires.
+func (s *Store) RevokeToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	ctx, done := s.instrument(ctx, "RevokeToken")
+	defer done()
+
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO revoked_tokens (jti, user_id, expires_at) VALUES ($1, $2, $3) ON CONFLICT (jti) DO NOTHING",
+		jti, userID, expiresAt,
+	)
+
+	return err
+}
+
+// ListRevokedTokens returns the denylisted tokens that are not expired yet.
+func (s *Store) ListRevokedTokens(ctx context.Context) ([]repository.RevokedToken, error) {
+	ctx, done := s.instrument(ctx, "ListRevokedTokens")
+	defer done()
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT jti, user_id, expires_at FROM revoked_tokens WHERE expires_at > $1",
+		time.Now(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []repository.RevokedToken
+	for rows.Next() {
+		var token repository.RevokedToken
+		if err := rows.Scan(&token.Jti, &token.UserId, &token.ExpiresAt); err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// PurgeRevokedTokens deletes the denylist entries that expired before now and
+// returns how many were removed.
+func (s *Store) PurgeRevokedTokens(ctx context.Context, now time.Time) (int64, error) {
+	ctx, done := s.instrument(ctx, "PurgeRevokedTokens")
+	defer done()
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM revoked_tokens WHERE expires_at <= $1", now)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// RevokeUserSessions rejects every token of userID issued before validAfter
+// and revokes all of their refresh tokens.
+func (s *Store) RevokeUserSessions(ctx context.Context, userID int, validAfter time.Time) error {
+	ctx, done := s.instrument(ctx, "RevokeUserSessions")
+	defer done()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE users SET tokens_valid_after = $1 WHERE id = $2",
+		validAfter, userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrUserNotFound
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL",
+		time.Now(), userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func createRefreshToken(ctx context.Context, db execer, param repository.CreateRefreshTokenParams) error {
+	_, err := db.ExecContext(ctx,
+		"INSERT INTO refresh_tokens (user_id, token_hash, family_id, expires_at) VALUES ($1, $2, $3, $4)",
+		param.UserId, param.TokenHash, param.FamilyId, param.ExpiresAt,
+	)
+
+	return err
+}
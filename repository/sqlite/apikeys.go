@@ -0,0 +1,154 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/NDOY3M4N/api-calculator/repository"
+)
+
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, scopes, created_at, last_used_at, revoked_at"
+
+func (s *Store) CreateAPIKey(ctx context.Context, param repository.CreateAPIKeyParams) (*repository.APIKey, error) {
+	ctx, done := s.instrument(ctx, "CreateAPIKey")
+	defer done()
+
+	scopes, err := json.Marshal(param.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		"INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes) VALUES (?, ?, ?, ?, ?)",
+		param.UserId, param.Name, param.Prefix, param.KeyHash, string(scopes),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.FindAPIKeyById(ctx, param.UserId, id)
+}
+
+func (s *Store) FindAPIKeyById(ctx context.Context, userID int, id int64) (*repository.APIKey, error) {
+	ctx, done := s.instrument(ctx, "FindAPIKeyById")
+	defer done()
+
+	row := s.db.QueryRowContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE id = ? AND user_id = ?", id, userID)
+
+	return findAPIKey(row)
+}
+
+func (s *Store) FindAPIKeyByPrefix(ctx context.Context, prefix string) (*repository.APIKey, error) {
+	ctx, done := s.instrument(ctx, "FindAPIKeyByPrefix")
+	defer done()
+
+	row := s.db.QueryRowContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE prefix = ?", prefix)
+
+	return findAPIKey(row)
+}
+
+func (s *Store) ListAPIKeys(ctx context.Context, userID int) ([]repository.APIKey, error) {
+	ctx, done := s.instrument(ctx, "ListAPIKeys")
+	defer done()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE user_id = ? ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []repository.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey revokes the key id of userID. Revoking a key twice is a no-op.
+func (s *Store) RevokeAPIKey(ctx context.Context, userID int, id int64) error {
+	ctx, done := s.instrument(ctx, "RevokeAPIKey")
+	defer done()
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ? AND user_id = ?",
+		time.Now().Format(timeLayout), id, userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) TouchAPIKey(ctx context.Context, id int64, usedAt time.Time) error {
+	ctx, done := s.instrument(ctx, "TouchAPIKey")
+	defer done()
+
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE api_keys SET last_used_at = ? WHERE id = ?",
+		usedAt.In(time.Local).Format(timeLayout), id,
+	)
+
+	return err
+}
+
+func findAPIKey(row *sql.Row) (*repository.APIKey, error) {
+	key, err := scanAPIKey(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func scanAPIKey(row scanner) (*repository.APIKey, error) {
+	var (
+		key        repository.APIKey
+		scopes     string
+		createdAt  string
+		lastUsedAt sql.NullString
+		revokedAt  sql.NullString
+	)
+
+	err := row.Scan(&key.Id, &key.UserId, &key.Name, &key.Prefix, &key.KeyHash, &scopes, &createdAt, &lastUsedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return nil, err
+	}
+
+	if key.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if key.LastUsedAt, err = parseNullTime(lastUsedAt); err != nil {
+		return nil, err
+	}
+	if key.RevokedAt, err = parseNullTime(revokedAt); err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
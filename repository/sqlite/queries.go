@@ -0,0 +1,381 @@
+// Package sqlite stores the data of the API in a SQLite database.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/NDOY3M4N/api-calculator/metrics"
+	"github.com/NDOY3M4N/api-calculator/repository"
+)
+
+// timeLayout is the format of the date columns. It matches what SQLite
+// produces for DATETIME('now', 'localtime'), so dates are stored in local time.
+const timeLayout = "2006-01-02 15:04:05"
+
+const userColumns = "id, pseudo, role, password_hash, failed_login_attempts, locked_until, tokens_valid_after"
+
+type Store struct {
+	db *sql.DB
+	// queryTimeout bounds each method, 0 disables it
+	queryTimeout time.Duration
+}
+
+var _ repository.Store = (*Store)(nil)
+
+// New returns the store of db, whose methods fail with
+// context.DeadlineExceeded after queryTimeout.
+func New(db *sql.DB, queryTimeout time.Duration) *Store {
+	return &Store{db: db, queryTimeout: queryTimeout}
+}
+
+func (s *Store) instrument(ctx context.Context, query string) (context.Context, func()) {
+	return repository.Instrument(ctx, semconv.DBSystemSqlite, query, s.queryTimeout)
+}
+
+// Ping checks that the database is reachable.
+func (s *Store) Ping(ctx context.Context) error {
+	ctx, done := s.instrument(ctx, "Ping")
+	defer done()
+
+	return s.db.PingContext(ctx)
+}
+
+// SchemaVersion returns the version of the last applied migration.
+func (s *Store) SchemaVersion(ctx context.Context) (int64, error) {
+	ctx, done := s.instrument(ctx, "SchemaVersion")
+	defer done()
+
+	var version sql.NullInt64
+	err := s.db.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_version").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return version.Int64, nil
+}
+
+func (s *Store) FindUserById(ctx context.Context, id int) (*repository.User, error) {
+	ctx, done := s.instrument(ctx, "FindUserById")
+	defer done()
+
+	row := s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = ?", id)
+
+	return s.find(row)
+}
+
+func (s *Store) FindUserByPseudo(ctx context.Context, pseudo string) (*repository.User, error) {
+	ctx, done := s.instrument(ctx, "FindUserByPseudo")
+	defer done()
+
+	row := s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE pseudo = ?", pseudo)
+
+	return s.find(row)
+}
+
+func (s *Store) find(row *sql.Row) (*repository.User, error) {
+	user, err := scanUser(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *Store) ListUsers(ctx context.Context) ([]repository.User, error) {
+	ctx, done := s.instrument(ctx, "ListUsers")
+	defer done()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []repository.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	return users, rows.Err()
+}
+
+func (s *Store) UpdateUserRole(ctx context.Context, id int, role repository.Role) error {
+	ctx, done := s.instrument(ctx, "UpdateUserRole")
+	defer done()
+
+	res, err := s.db.ExecContext(ctx, "UPDATE users SET role = ? WHERE id = ?", role, id)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func scanUser(row scanner) (*repository.User, error) {
+	var (
+		user         = new(repository.User)
+		passwordHash sql.NullString
+		lockedUntil  sql.NullString
+		validAfter   sql.NullString
+	)
+
+	err := row.Scan(&user.Id, &user.Pseudo, &user.Role, &passwordHash, &user.FailedLoginAttempts, &lockedUntil, &validAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	user.PasswordHash = passwordHash.String
+	if user.LockedUntil, err = parseNullTime(lockedUntil); err != nil {
+		return nil, err
+	}
+	if user.TokensValidAfter, err = parseNullTime(validAfter); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *Store) CreateUser(ctx context.Context, pseudo, passwordHash string) (*repository.User, error) {
+	ctx, done := s.instrument(ctx, "CreateUser")
+	defer done()
+
+	res, err := s.db.ExecContext(ctx, "INSERT INTO users (pseudo, password_hash) VALUES (?, ?)", pseudo, passwordHash)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return nil, repository.ErrPseudoTaken
+		}
+		return nil, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &repository.User{Id: id, Pseudo: pseudo, Role: repository.RoleUser, PasswordHash: passwordHash}, nil
+}
+
+// RecordLoginFailure counts a failed login attempt. Once maxAttempts is
+// reached the user is locked until now+lockout and the counter starts over.
+func (s *Store) RecordLoginFailure(ctx context.Context, userID int64, maxAttempts int, lockout time.Duration) error {
+	ctx, done := s.instrument(ctx, "RecordLoginFailure")
+	defer done()
+
+	lockedUntil := time.Now().Add(lockout).Format(timeLayout)
+
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE users SET
+			failed_login_attempts = CASE WHEN failed_login_attempts + 1 >= ? THEN 0 ELSE failed_login_attempts + 1 END,
+			locked_until = CASE WHEN failed_login_attempts + 1 >= ? THEN ? ELSE locked_until END
+		WHERE id = ?`,
+		maxAttempts, maxAttempts, lockedUntil, userID,
+	)
+
+	return err
+}
+
+// ResetLoginFailures clears the failed attempts and the lock of a user after
+// a successful login.
+func (s *Store) ResetLoginFailures(ctx context.Context, userID int64) error {
+	ctx, done := s.instrument(ctx, "ResetLoginFailures")
+	defer done()
+
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE users SET failed_login_attempts = 0, locked_until = NULL WHERE id = ?",
+		userID,
+	)
+
+	return err
+}
+
+func (s *Store) AddOperation(ctx context.Context, param repository.AddOperationParams) error {
+	ctx, done := s.instrument(ctx, "AddOperation")
+	defer done()
+
+	args := make([]interface{}, 0, len(param.Inputs)+3)
+	for _, input := range param.Inputs {
+		args = append(args, input)
+	}
+	if param.Mode == "" {
+		param.Mode = repository.ModeFloat
+	}
+	args = append(args, param.Type, param.Result, param.Mode, param.UserId, param.Expression, param.RequestId)
+
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO operations (inputs, type, result, mode, user_id, expression, request_id) VALUES (JSON_ARRAY("+strings.TrimSuffix(strings.Repeat("?,", len(param.Inputs)), ",")+"), ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))",
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+
+	metrics.OperationsRecorded.WithLabelValues(string(param.Type), string(param.Mode)).Inc()
+
+	return nil
+}
+
+// numericResult is the result of an operation as a number, NULL for the
+// JSON results of the statistics, vectors and matrices and for the complex
+// numbers, which the result filters skip. The fractions of the fraction mode
+// are divided, CAST alone would read "2/3" as 2.
+const numericResult = "(CASE" +
+	" WHEN result GLOB '*i' THEN NULL" +
+	" WHEN result GLOB '[-0-9]*/*' THEN CAST(substr(result, 1, instr(result, '/') - 1) AS REAL) / CAST(substr(result, instr(result, '/') + 1) AS REAL)" +
+	" WHEN result GLOB '[-0-9]*' THEN CAST(result AS REAL)" +
+	" END)"
+
+const operationColumns = "id, inputs, type, result, mode, user_id, expression, request_id, created_at"
+
+func (s *Store) ListOperations(ctx context.Context, params repository.ListOperationsParams) ([]repository.Operations, error) {
+	ctx, done := s.instrument(ctx, "ListOperations")
+	defer done()
+
+	var (
+		where = []string{"user_id = ?"}
+		args  = []any{params.UserId}
+	)
+
+	if params.Type != "" {
+		where = append(where, "type = ?")
+		args = append(args, params.Type)
+	}
+	if params.RequestId != "" {
+		where = append(where, "request_id = ?")
+		args = append(args, params.RequestId)
+	}
+	if !params.From.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, params.From.In(time.Local).Format(timeLayout))
+	}
+	if !params.To.IsZero() {
+		where = append(where, "created_at <= ?")
+		args = append(args, params.To.In(time.Local).Format(timeLayout))
+	}
+	if params.MinResult != nil {
+		where = append(where, numericResult+" >= ?")
+		args = append(args, *params.MinResult)
+	}
+	if params.MaxResult != nil {
+		where = append(where, numericResult+" <= ?")
+		args = append(args, *params.MaxResult)
+	}
+
+	order := "DESC"
+	if params.Order == repository.SortAsc {
+		order = "ASC"
+	}
+
+	if params.AfterId > 0 {
+		if order == "ASC" {
+			where = append(where, "id > ?")
+		} else {
+			where = append(where, "id < ?")
+		}
+		args = append(args, params.AfterId)
+	}
+
+	query := "SELECT " + operationColumns + " FROM operations WHERE " + strings.Join(where, " AND ") + " ORDER BY id " + order
+	if params.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, params.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	operations := []repository.Operations{}
+	for rows.Next() {
+		operation, err := scanOperation(rows)
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, *operation)
+	}
+
+	return operations, rows.Err()
+}
+
+func (s *Store) FindOperationById(ctx context.Context, userID int, id int64) (*repository.Operations, error) {
+	ctx, done := s.instrument(ctx, "FindOperationById")
+	defer done()
+
+	row := s.db.QueryRowContext(ctx, "SELECT "+operationColumns+" FROM operations WHERE id = ? AND user_id = ?", id, userID)
+
+	operation, err := scanOperation(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrOperationNotFound
+		}
+		return nil, err
+	}
+
+	return operation, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOperation(row scanner) (*repository.Operations, error) {
+	var (
+		operation  repository.Operations
+		inputs     string
+		expression sql.NullString
+		requestID  sql.NullString
+		createdAt  string
+	)
+
+	err := row.Scan(
+		&operation.Id,
+		&inputs,
+		&operation.Type,
+		&operation.Result,
+		&operation.Mode,
+		&operation.UserId,
+		&expression,
+		&requestID,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(inputs), &operation.Inputs); err != nil {
+		return nil, err
+	}
+
+	operation.Expression = expression.String
+	operation.RequestId = requestID.String
+	operation.CreatedAt, err = parseTime(createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &operation, nil
+}
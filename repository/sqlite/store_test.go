@@ -0,0 +1,31 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/NDOY3M4N/api-calculator/migrations"
+	"github.com/NDOY3M4N/api-calculator/repository"
+	"github.com/NDOY3M4N/api-calculator/repository/sqlite"
+	"github.com/NDOY3M4N/api-calculator/repository/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) repository.Store {
+		db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "database.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		if _, err := migrations.New(db, migrations.SQLite).Up(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		return sqlite.New(db, 0)
+	})
+}
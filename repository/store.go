@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrUserNotFound         = errors.New("user not found")
+	ErrPseudoTaken          = errors.New("pseudo already taken")
+	ErrOperationNotFound    = errors.New("operation not found")
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenUsed     = errors.New("refresh token already used")
+	ErrAPIKeyNotFound       = errors.New("api key not found")
+)
+
+// Store persists the users, their operations and their credentials. It is
+// implemented for SQLite by the sqlite package and for PostgreSQL by the
+// postgres package, which storetest checks against the same suite.
+type Store interface {
+	// Ping checks that the database is reachable.
+	Ping(ctx context.Context) error
+	// SchemaVersion returns the version of the last applied migration.
+	SchemaVersion(ctx context.Context) (int64, error)
+
+	FindUserById(ctx context.Context, id int) (*User, error)
+	FindUserByPseudo(ctx context.Context, pseudo string) (*User, error)
+	ListUsers(ctx context.Context) ([]User, error)
+	// CreateUser fails with ErrPseudoTaken when pseudo is already used.
+	CreateUser(ctx context.Context, pseudo, passwordHash string) (*User, error)
+	UpdateUserRole(ctx context.Context, id int, role Role) error
+	// RecordLoginFailure counts a failed login attempt. Once maxAttempts is
+	// reached the user is locked until now+lockout and the counter starts over.
+	RecordLoginFailure(ctx context.Context, userID int64, maxAttempts int, lockout time.Duration) error
+	// ResetLoginFailures clears the failed attempts and the lock of a user
+	// after a successful login.
+	ResetLoginFailures(ctx context.Context, userID int64) error
+
+	AddOperation(ctx context.Context, param AddOperationParams) error
+	ListOperations(ctx context.Context, params ListOperationsParams) ([]Operations, error)
+	FindOperationById(ctx context.Context, userID int, id int64) (*Operations, error)
+
+	CreateRefreshToken(ctx context.Context, param CreateRefreshTokenParams) error
+	FindRefreshToken(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	// RotateRefreshToken revokes the token oldID and stores next in its
+	// place. It fails with ErrRefreshTokenUsed when oldID was already
+	// revoked, which also protects against two concurrent rotations of the
+	// same token.
+	RotateRefreshToken(ctx context.Context, oldID int64, next CreateRefreshTokenParams) error
+	// RevokeRefreshTokenFamily revokes every token obtained from the same
+	// login.
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	// RevokeToken adds the access token jti to the denylist until it
+	// expires. Revoking a token twice is a no-op.
+	RevokeToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error
+	// ListRevokedTokens returns the denylisted tokens that are not expired
+	// yet.
+	ListRevokedTokens(ctx context.Context) ([]RevokedToken, error)
+	// PurgeRevokedTokens deletes the denylist entries that expired before
+	// now and returns how many were removed.
+	PurgeRevokedTokens(ctx context.Context, now time.Time) (int64, error)
+	// RevokeUserSessions rejects every token of userID issued before
+	// validAfter and revokes all of their refresh tokens.
+	RevokeUserSessions(ctx context.Context, userID int, validAfter time.Time) error
+
+	CreateAPIKey(ctx context.Context, param CreateAPIKeyParams) (*APIKey, error)
+	FindAPIKeyById(ctx context.Context, userID int, id int64) (*APIKey, error)
+	FindAPIKeyByPrefix(ctx context.Context, prefix string) (*APIKey, error)
+	ListAPIKeys(ctx context.Context, userID int) ([]APIKey, error)
+	// RevokeAPIKey revokes the key id of userID. Revoking a key twice is a
+	// no-op.
+	RevokeAPIKey(ctx context.Context, userID int, id int64) error
+	TouchAPIKey(ctx context.Context, id int64, usedAt time.Time) error
+}
+
+// AddOperationParams holds the inputs and result of an operation as decimal
+// text so that both float and decimal mode values are stored losslessly.
+type AddOperationParams struct {
+	Inputs     []string
+	Type       OperationType
+	Result     string
+	Mode       OperationMode
+	UserId     int
+	Expression string
+	RequestId  string
+}
+
+type SortOrder string
+
+const (
+	SortAsc  SortOrder = "asc"
+	SortDesc SortOrder = "desc"
+)
+
+// ListOperationsParams filters the operations of a user. Zero values disable
+// the corresponding filter.
+type ListOperationsParams struct {
+	UserId    int
+	Type      OperationType
+	RequestId string
+	From      time.Time
+	To        time.Time
+	MinResult *float64
+	MaxResult *float64
+	Order     SortOrder
+	// AfterId is the keyset cursor: only operations that come after this id
+	// in the requested order are returned
+	AfterId int64
+	Limit   int
+}
+
+type CreateRefreshTokenParams struct {
+	UserId    int64
+	TokenHash string
+	FamilyId  string
+	ExpiresAt time.Time
+}
+
+type CreateAPIKeyParams struct {
+	UserId  int
+	Name    string
+	Prefix  string
+	KeyHash string
+	Scopes  []string
+}
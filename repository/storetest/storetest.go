@@ -0,0 +1,474 @@
+// Package storetest checks that an implementation of repository.Store
+// behaves like the others. It is run by the tests of each store package.
+package storetest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/NDOY3M4N/api-calculator/repository"
+)
+
+// tolerance absorbs the rounding of the dates to the second by SQLite.
+const tolerance = 2 * time.Second
+
+// Run runs the conformance suite against the store returned by newStore,
+// whose schema should be up to date. The rows it creates have a random
+// suffix, so it can run against a database that is not empty.
+func Run(t *testing.T, newStore func(t *testing.T) repository.Store) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatal(err)
+	}
+
+	store := newStore(t)
+	for _, check := range []struct {
+		name string
+		fn   func(*suite)
+	}{
+		{"Schema", (*suite).schema},
+		{"Users", (*suite).users},
+		{"LoginFailures", (*suite).loginFailures},
+		{"Operations", (*suite).operations},
+		{"RefreshTokens", (*suite).refreshTokens},
+		{"RevokedTokens", (*suite).revokedTokens},
+		{"UserSessions", (*suite).userSessions},
+		{"APIKeys", (*suite).apiKeys},
+	} {
+		t.Run(check.name, func(t *testing.T) {
+			check.fn(&suite{t: t, ctx: context.Background(), store: store, suffix: hex.EncodeToString(suffix)})
+		})
+	}
+}
+
+type suite struct {
+	t      *testing.T
+	ctx    context.Context
+	store  repository.Store
+	suffix string
+}
+
+func (s *suite) errorf(format string, args ...any) {
+	s.t.Helper()
+	s.t.Errorf(format, args...)
+}
+
+// expectErr records a failure when err is not target.
+func (s *suite) expectErr(name string, err, target error) {
+	s.t.Helper()
+	if !errors.Is(err, target) {
+		s.errorf("%s: got error %v, expected %v", name, err, target)
+	}
+}
+
+// createUser creates a user with a pseudo unique to the run, nil on failure.
+func (s *suite) createUser(name string) *repository.User {
+	user, err := s.store.CreateUser(s.ctx, name+"-"+s.suffix, "hash-"+name)
+	if err != nil {
+		s.errorf("CreateUser(%s): %v", name, err)
+		return nil
+	}
+
+	return user
+}
+
+func (s *suite) schema() {
+	if err := s.store.Ping(s.ctx); err != nil {
+		s.errorf("Ping: %v", err)
+	}
+
+	version, err := s.store.SchemaVersion(s.ctx)
+	if err != nil {
+		s.errorf("SchemaVersion: %v", err)
+	} else if version <= 0 {
+		s.errorf("SchemaVersion: got %d, expected a migration version", version)
+	}
+}
+
+func (s *suite) users() {
+	user := s.createUser("alice")
+	if user == nil {
+		return
+	}
+	if user.Id <= 0 || user.Role != repository.RoleUser || user.PasswordHash != "hash-alice" {
+		s.errorf("CreateUser: got %+v", user)
+	}
+
+	_, err := s.store.CreateUser(s.ctx, user.Pseudo, "other-hash")
+	s.expectErr("CreateUser with a taken pseudo", err, repository.ErrPseudoTaken)
+
+	found, err := s.store.FindUserById(s.ctx, int(user.Id))
+	if err != nil {
+		s.errorf("FindUserById: %v", err)
+	} else if found.Pseudo != user.Pseudo || found.Role != repository.RoleUser || found.PasswordHash != user.PasswordHash ||
+		found.FailedLoginAttempts != 0 || found.LockedUntil != nil || found.TokensValidAfter != nil {
+		s.errorf("FindUserById: got %+v, expected %+v", found, user)
+	}
+
+	found, err = s.store.FindUserByPseudo(s.ctx, user.Pseudo)
+	if err != nil {
+		s.errorf("FindUserByPseudo: %v", err)
+	} else if found.Id != user.Id {
+		s.errorf("FindUserByPseudo: got user %d, expected %d", found.Id, user.Id)
+	}
+
+	_, err = s.store.FindUserByPseudo(s.ctx, "nobody-"+s.suffix)
+	s.expectErr("FindUserByPseudo of an unknown pseudo", err, repository.ErrUserNotFound)
+	_, err = s.store.FindUserById(s.ctx, -1)
+	s.expectErr("FindUserById of an unknown id", err, repository.ErrUserNotFound)
+
+	if err := s.store.UpdateUserRole(s.ctx, int(user.Id), repository.RoleAdmin); err != nil {
+		s.errorf("UpdateUserRole: %v", err)
+	} else if found, err := s.store.FindUserById(s.ctx, int(user.Id)); err != nil || found.Role != repository.RoleAdmin {
+		s.errorf("UpdateUserRole: role not updated (%v)", err)
+	}
+	s.expectErr("UpdateUserRole of an unknown id", s.store.UpdateUserRole(s.ctx, -1, repository.RoleUser), repository.ErrUserNotFound)
+
+	users, err := s.store.ListUsers(s.ctx)
+	if err != nil {
+		s.errorf("ListUsers: %v", err)
+	} else if !slices.ContainsFunc(users, func(u repository.User) bool { return u.Id == user.Id }) {
+		s.errorf("ListUsers: user %d missing", user.Id)
+	} else if !slices.IsSortedFunc(users, func(a, b repository.User) int { return int(a.Id - b.Id) }) {
+		s.errorf("ListUsers: users not sorted by id")
+	}
+}
+
+func (s *suite) loginFailures() {
+	user := s.createUser("bob")
+	if user == nil {
+		return
+	}
+
+	attempts := func() *repository.User {
+		found, err := s.store.FindUserById(s.ctx, int(user.Id))
+		if err != nil {
+			s.errorf("FindUserById: %v", err)
+			return &repository.User{}
+		}
+		return found
+	}
+
+	if err := s.store.RecordLoginFailure(s.ctx, user.Id, 2, time.Hour); err != nil {
+		s.errorf("RecordLoginFailure: %v", err)
+		return
+	}
+	if found := attempts(); found.FailedLoginAttempts != 1 || found.LockedUntil != nil {
+		s.errorf("RecordLoginFailure: got %d attempts and lock %v, expected 1 and none", found.FailedLoginAttempts, found.LockedUntil)
+	}
+
+	if err := s.store.RecordLoginFailure(s.ctx, user.Id, 2, time.Hour); err != nil {
+		s.errorf("RecordLoginFailure: %v", err)
+		return
+	}
+	found := attempts()
+	if found.FailedLoginAttempts != 0 || found.LockedUntil == nil {
+		s.errorf("RecordLoginFailure: got %d attempts and lock %v, expected 0 and a lock", found.FailedLoginAttempts, found.LockedUntil)
+	} else if lockedFor := time.Until(*found.LockedUntil); lockedFor < time.Hour-tolerance || lockedFor > time.Hour+tolerance {
+		s.errorf("RecordLoginFailure: locked for %s, expected 1h", lockedFor)
+	}
+
+	if err := s.store.ResetLoginFailures(s.ctx, user.Id); err != nil {
+		s.errorf("ResetLoginFailures: %v", err)
+	} else if found := attempts(); found.FailedLoginAttempts != 0 || found.LockedUntil != nil {
+		s.errorf("ResetLoginFailures: got %d attempts and lock %v", found.FailedLoginAttempts, found.LockedUntil)
+	}
+}
+
+func (s *suite) operations() {
+	user, other := s.createUser("carol"), s.createUser("dave")
+	if user == nil || other == nil {
+		return
+	}
+
+	requestID := "req-" + s.suffix
+	params := []repository.AddOperationParams{
+		{Inputs: []string{"1", "2"}, Type: repository.TypeAdd, Result: "3", UserId: int(user.Id)},
+		{Inputs: []string{"0.1", "0.2"}, Type: repository.TypeAdd, Result: "0.3", Mode: repository.ModeDecimal, UserId: int(user.Id), RequestId: requestID},
+		{Inputs: []string{}, Type: repository.TypeEvaluate, Result: "-4.5", UserId: int(user.Id), Expression: "(1 - 10) / 2"},
+		{Inputs: []string{"5", "5"}, Type: repository.TypeMultiply, Result: "25", UserId: int(other.Id)},
+		{Inputs: []string{"2", "10"}, Type: repository.TypePow, Result: "1024", UserId: int(other.Id), Expression: "pow(2, 10)"},
+		{Inputs: []string{"1", "3"}, Type: repository.TypeStats, Result: `{"count":2,"mean":2}`, UserId: int(other.Id)},
+		{Inputs: []string{"[[1,2],[3,4]]"}, Type: repository.TypeMatrixTranspose, Result: "[[1,3],[2,4]]", UserId: int(other.Id)},
+		{Inputs: []string{"1/3", "1/3"}, Type: repository.TypeAdd, Result: "2/3", Mode: repository.ModeFraction, UserId: int(other.Id)},
+		{Inputs: []string{"0+1i", "0+1i"}, Type: repository.TypeComplexMultiply, Result: "-1+0i", UserId: int(other.Id)},
+	}
+	for _, param := range params {
+		if err := s.store.AddOperation(s.ctx, param); err != nil {
+			s.errorf("AddOperation(%+v): %v", param, err)
+			return
+		}
+	}
+
+	list := func(name string, p repository.ListOperationsParams) []repository.Operations {
+		p.UserId = int(user.Id)
+		operations, err := s.store.ListOperations(s.ctx, p)
+		if err != nil {
+			s.errorf("ListOperations %s: %v", name, err)
+		}
+		return operations
+	}
+	results := func(operations []repository.Operations) []string {
+		var results []string
+		for _, operation := range operations {
+			results = append(results, operation.Result)
+		}
+		return results
+	}
+	expect := func(name string, operations []repository.Operations, expected ...string) {
+		s.t.Helper()
+		if got := results(operations); !slices.Equal(got, expected) {
+			s.errorf("ListOperations %s: got results %v, expected %v", name, got, expected)
+		}
+	}
+
+	all := list("of a user", repository.ListOperationsParams{})
+	expect("of a user", all, "-4.5", "0.3", "3")
+	if len(all) != 3 {
+		return
+	}
+
+	decimal := all[1]
+	if !slices.Equal(decimal.Inputs, []string{"0.1", "0.2"}) || decimal.Type != repository.TypeAdd ||
+		decimal.Mode != repository.ModeDecimal || decimal.UserId != user.Id || decimal.RequestId != requestID || decimal.Expression != "" {
+		s.errorf("ListOperations: got %+v", decimal)
+	}
+	if age := time.Since(decimal.CreatedAt); age < -tolerance || age > time.Minute {
+		s.errorf("ListOperations: created at %s, expected now", decimal.CreatedAt)
+	}
+	if all[2].Mode != repository.ModeFloat {
+		s.errorf("ListOperations: got mode %q, expected the float default", all[2].Mode)
+	}
+	if all[0].Expression != "(1 - 10) / 2" || len(all[0].Inputs) != 0 {
+		s.errorf("ListOperations: got %+v", all[0])
+	}
+
+	minResult, maxResult := 0.0, 1.0
+	expect("in ascending order", list("in ascending order", repository.ListOperationsParams{Order: repository.SortAsc}), "3", "0.3", "-4.5")
+	expect("by type", list("by type", repository.ListOperationsParams{Type: repository.TypeAdd}), "0.3", "3")
+	expect("by request ID", list("by request ID", repository.ListOperationsParams{RequestId: requestID}), "0.3")
+	expect("by result", list("by result", repository.ListOperationsParams{MinResult: &minResult, MaxResult: &maxResult}), "0.3")
+
+	// The JSON results of the statistics and matrices and the complex numbers
+	// are not compared, even to 0, and fractions are compared by their value
+	operations, err := s.store.ListOperations(s.ctx, repository.ListOperationsParams{UserId: int(other.Id), MaxResult: &maxResult})
+	if err != nil {
+		s.errorf("ListOperations by result with statistics: %v", err)
+	}
+	expect("by result with statistics", operations, "2/3")
+	expect("after a cursor", list("after a cursor", repository.ListOperationsParams{AfterId: all[0].Id, Limit: 1}), "0.3")
+	expect("after a cursor in ascending order", list("after a cursor in ascending order", repository.ListOperationsParams{Order: repository.SortAsc, AfterId: all[2].Id}), "0.3", "-4.5")
+	expect("from now on", list("from now on", repository.ListOperationsParams{From: time.Now().Add(time.Hour)}))
+	expect("until now", list("until now", repository.ListOperationsParams{To: time.Now().Add(time.Hour)}), "-4.5", "0.3", "3")
+
+	found, err := s.store.FindOperationById(s.ctx, int(user.Id), decimal.Id)
+	if err != nil {
+		s.errorf("FindOperationById: %v", err)
+	} else if found.Result != "0.3" || !slices.Equal(found.Inputs, decimal.Inputs) {
+		s.errorf("FindOperationById: got %+v", found)
+	}
+	_, err = s.store.FindOperationById(s.ctx, int(other.Id), decimal.Id)
+	s.expectErr("FindOperationById of another user", err, repository.ErrOperationNotFound)
+}
+
+func (s *suite) refreshTokens() {
+	user := s.createUser("erin")
+	if user == nil {
+		return
+	}
+
+	first := repository.CreateRefreshTokenParams{
+		UserId:    user.Id,
+		TokenHash: "first-" + s.suffix,
+		FamilyId:  "family-" + s.suffix,
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+	if err := s.store.CreateRefreshToken(s.ctx, first); err != nil {
+		s.errorf("CreateRefreshToken: %v", err)
+		return
+	}
+
+	token, err := s.store.FindRefreshToken(s.ctx, first.TokenHash)
+	if err != nil {
+		s.errorf("FindRefreshToken: %v", err)
+		return
+	}
+	if token.UserId != user.Id || token.FamilyId != first.FamilyId || token.RevokedAt != nil || !near(token.ExpiresAt, first.ExpiresAt) {
+		s.errorf("FindRefreshToken: got %+v, expected %+v", token, first)
+	}
+
+	_, err = s.store.FindRefreshToken(s.ctx, "unknown-"+s.suffix)
+	s.expectErr("FindRefreshToken of an unknown hash", err, repository.ErrRefreshTokenNotFound)
+
+	second := first
+	second.TokenHash = "second-" + s.suffix
+	if err := s.store.RotateRefreshToken(s.ctx, token.Id, second); err != nil {
+		s.errorf("RotateRefreshToken: %v", err)
+		return
+	}
+	if rotated, err := s.store.FindRefreshToken(s.ctx, first.TokenHash); err != nil || rotated.RevokedAt == nil {
+		s.errorf("RotateRefreshToken: the old token is not revoked (%v)", err)
+	}
+
+	third := first
+	third.TokenHash = "third-" + s.suffix
+	s.expectErr("RotateRefreshToken of a used token", s.store.RotateRefreshToken(s.ctx, token.Id, third), repository.ErrRefreshTokenUsed)
+	if _, err := s.store.FindRefreshToken(s.ctx, third.TokenHash); !errors.Is(err, repository.ErrRefreshTokenNotFound) {
+		s.errorf("RotateRefreshToken of a used token: the new token is stored (%v)", err)
+	}
+
+	if err := s.store.RevokeRefreshTokenFamily(s.ctx, first.FamilyId); err != nil {
+		s.errorf("RevokeRefreshTokenFamily: %v", err)
+	} else if token, err := s.store.FindRefreshToken(s.ctx, second.TokenHash); err != nil || token.RevokedAt == nil {
+		s.errorf("RevokeRefreshTokenFamily: the token is not revoked (%v)", err)
+	}
+}
+
+func (s *suite) revokedTokens() {
+	user := s.createUser("frank")
+	if user == nil {
+		return
+	}
+
+	active, expired := "active-"+s.suffix, "expired-"+s.suffix
+	expiresAt := time.Now().Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if err := s.store.RevokeToken(s.ctx, active, int(user.Id), expiresAt); err != nil {
+			s.errorf("RevokeToken (%d): %v", i+1, err)
+		}
+	}
+	if err := s.store.RevokeToken(s.ctx, expired, int(user.Id), time.Now().Add(-time.Hour)); err != nil {
+		s.errorf("RevokeToken: %v", err)
+	}
+
+	tokens, err := s.store.ListRevokedTokens(s.ctx)
+	if err != nil {
+		s.errorf("ListRevokedTokens: %v", err)
+	}
+	i := slices.IndexFunc(tokens, func(t repository.RevokedToken) bool { return t.Jti == active })
+	if i < 0 {
+		s.errorf("ListRevokedTokens: %s missing", active)
+	} else if tokens[i].UserId != user.Id || !near(tokens[i].ExpiresAt, expiresAt) {
+		s.errorf("ListRevokedTokens: got %+v", tokens[i])
+	}
+	if slices.ContainsFunc(tokens, func(t repository.RevokedToken) bool { return t.Jti == expired }) {
+		s.errorf("ListRevokedTokens: the expired %s is listed", expired)
+	}
+
+	if n, err := s.store.PurgeRevokedTokens(s.ctx, time.Now()); err != nil {
+		s.errorf("PurgeRevokedTokens: %v", err)
+	} else if n < 1 {
+		s.errorf("PurgeRevokedTokens: %d tokens purged, expected at least 1", n)
+	}
+	tokens, _ = s.store.ListRevokedTokens(s.ctx)
+	if !slices.ContainsFunc(tokens, func(t repository.RevokedToken) bool { return t.Jti == active }) {
+		s.errorf("PurgeRevokedTokens: %s purged before its expiry", active)
+	}
+}
+
+func (s *suite) userSessions() {
+	user := s.createUser("grace")
+	if user == nil {
+		return
+	}
+
+	token := repository.CreateRefreshTokenParams{
+		UserId:    user.Id,
+		TokenHash: "session-" + s.suffix,
+		FamilyId:  "session-family-" + s.suffix,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	if err := s.store.CreateRefreshToken(s.ctx, token); err != nil {
+		s.errorf("CreateRefreshToken: %v", err)
+		return
+	}
+
+	validAfter := time.Now()
+	if err := s.store.RevokeUserSessions(s.ctx, int(user.Id), validAfter); err != nil {
+		s.errorf("RevokeUserSessions: %v", err)
+		return
+	}
+	if found, err := s.store.FindUserById(s.ctx, int(user.Id)); err != nil || found.TokensValidAfter == nil || !near(*found.TokensValidAfter, validAfter) {
+		s.errorf("RevokeUserSessions: tokens_valid_after not set (%v)", err)
+	}
+	if found, err := s.store.FindRefreshToken(s.ctx, token.TokenHash); err != nil || found.RevokedAt == nil {
+		s.errorf("RevokeUserSessions: the refresh token is not revoked (%v)", err)
+	}
+
+	s.expectErr("RevokeUserSessions of an unknown user", s.store.RevokeUserSessions(s.ctx, -1, validAfter), repository.ErrUserNotFound)
+}
+
+func (s *suite) apiKeys() {
+	user, other := s.createUser("heidi"), s.createUser("ivan")
+	if user == nil || other == nil {
+		return
+	}
+
+	key, err := s.store.CreateAPIKey(s.ctx, repository.CreateAPIKeyParams{
+		UserId:  int(user.Id),
+		Name:    "ci",
+		Prefix:  "calc_" + s.suffix,
+		KeyHash: "key-hash",
+		Scopes:  []string{"operations:read", "operations:write"},
+	})
+	if err != nil {
+		s.errorf("CreateAPIKey: %v", err)
+		return
+	}
+	if key.Id <= 0 || key.UserId != user.Id || key.Name != "ci" || key.KeyHash != "key-hash" ||
+		!slices.Equal(key.Scopes, []string{"operations:read", "operations:write"}) ||
+		!near(key.CreatedAt, time.Now()) || key.LastUsedAt != nil || key.RevokedAt != nil {
+		s.errorf("CreateAPIKey: got %+v", key)
+	}
+
+	if found, err := s.store.FindAPIKeyById(s.ctx, int(user.Id), key.Id); err != nil || found.Prefix != key.Prefix {
+		s.errorf("FindAPIKeyById: got %+v (%v)", found, err)
+	}
+	if found, err := s.store.FindAPIKeyByPrefix(s.ctx, key.Prefix); err != nil || found.Id != key.Id {
+		s.errorf("FindAPIKeyByPrefix: got %+v (%v)", found, err)
+	}
+	_, err = s.store.FindAPIKeyById(s.ctx, int(other.Id), key.Id)
+	s.expectErr("FindAPIKeyById of another user", err, repository.ErrAPIKeyNotFound)
+	_, err = s.store.FindAPIKeyByPrefix(s.ctx, "calc_unknown"+s.suffix)
+	s.expectErr("FindAPIKeyByPrefix of an unknown prefix", err, repository.ErrAPIKeyNotFound)
+
+	if keys, err := s.store.ListAPIKeys(s.ctx, int(user.Id)); err != nil || len(keys) != 1 || keys[0].Id != key.Id {
+		s.errorf("ListAPIKeys: got %+v (%v)", keys, err)
+	}
+	if keys, err := s.store.ListAPIKeys(s.ctx, int(other.Id)); err != nil || keys == nil || len(keys) != 0 {
+		s.errorf("ListAPIKeys of a user without keys: got %#v (%v), expected an empty list", keys, err)
+	}
+
+	usedAt := time.Now()
+	if err := s.store.TouchAPIKey(s.ctx, key.Id, usedAt); err != nil {
+		s.errorf("TouchAPIKey: %v", err)
+	} else if found, err := s.store.FindAPIKeyById(s.ctx, int(user.Id), key.Id); err != nil || found.LastUsedAt == nil || !near(*found.LastUsedAt, usedAt) {
+		s.errorf("TouchAPIKey: last_used_at not set (%v)", err)
+	}
+
+	if err := s.store.RevokeAPIKey(s.ctx, int(user.Id), key.Id); err != nil {
+		s.errorf("RevokeAPIKey: %v", err)
+	}
+	found, err := s.store.FindAPIKeyById(s.ctx, int(user.Id), key.Id)
+	if err != nil || found.RevokedAt == nil {
+		s.errorf("RevokeAPIKey: the key is not revoked (%v)", err)
+		return
+	}
+	if err := s.store.RevokeAPIKey(s.ctx, int(user.Id), key.Id); err != nil {
+		s.errorf("RevokeAPIKey twice: %v", err)
+	} else if again, err := s.store.FindAPIKeyById(s.ctx, int(user.Id), key.Id); err != nil || again.RevokedAt == nil || !again.RevokedAt.Equal(*found.RevokedAt) {
+		s.errorf("RevokeAPIKey twice: the revocation date changed (%v)", err)
+	}
+	s.expectErr("RevokeAPIKey of another user", s.store.RevokeAPIKey(s.ctx, int(other.Id), key.Id), repository.ErrAPIKeyNotFound)
+}
+
+// near tells whether a stored date matches the expected one, to the second.
+func near(got, expected time.Time) bool {
+	diff := got.Sub(expected)
+	return diff > -tolerance && diff < tolerance
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/NDOY3M4N/api-calculator/repository"
+)
+
+const revocationSyncInterval = time.Minute
+
+// RevocationList is an in-memory copy of the revoked_tokens denylist, so that
+// IsAuthenticated does not need a query per request to check a token.
+// Revocations go through the list, which writes them to the database first.
+type RevocationList struct {
+	repo repository.Store
+
+	mu   sync.RWMutex
+	jtis map[string]time.Time
+}
+
+func NewRevocationList(repo repository.Store) *RevocationList {
+	return &RevocationList{
+		repo: repo,
+		jtis: make(map[string]time.Time),
+	}
+}
+
+// Load merges the non-expired entries of the database into the cache, which
+// picks up the revocations made by other instances, and drops the entries that
+// expired. The cache is not replaced, so that a token revoked while the query
+// runs, missing from its rows, is kept.
+func (rl *RevocationList) Load(ctx context.Context) error {
+	tokens, err := rl.repo.ListRevokedTokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	for jti, expiresAt := range rl.jtis {
+		if !expiresAt.After(now) {
+			delete(rl.jtis, jti)
+		}
+	}
+	for _, token := range tokens {
+		rl.jtis[token.Jti] = token.ExpiresAt
+	}
+
+	return nil
+}
+
+// Start purges the expired entries and reloads the cache every interval until
+// ctx is cancelled.
+func (rl *RevocationList) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				rl.sync(ctx, now)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (rl *RevocationList) sync(ctx context.Context, now time.Time) {
+	purged, err := rl.repo.PurgeRevokedTokens(ctx, now)
+	if err != nil {
+		logger.Error("Purge revoked tokens", slog.String("message", err.Error()))
+	} else if purged > 0 {
+		logger.Info("Purged expired revoked tokens", slog.Int64("count", purged))
+	}
+
+	if err := rl.Load(ctx); err != nil {
+		logger.Error("Load revoked tokens", slog.String("message", err.Error()))
+	}
+}
+
+func (rl *RevocationList) IsRevoked(jti string) bool {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+
+	_, ok := rl.jtis[jti]
+	return ok
+}
+
+// Revoke denylists the token jti of userID until it expires.
+func (rl *RevocationList) Revoke(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	if err := rl.repo.RevokeToken(ctx, jti, userID, expiresAt); err != nil {
+		return err
+	}
+
+	rl.mu.Lock()
+	rl.jtis[jti] = expiresAt
+	rl.mu.Unlock()
+
+	return nil
+}
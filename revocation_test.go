@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NDOY3M4N/api-calculator/repository"
+)
+
+// revokedTokensStore returns tokens from ListRevokedTokens, after calling
+// during, so that a revocation can happen while the list is loaded.
+type revokedTokensStore struct {
+	repository.Store
+	tokens []repository.RevokedToken
+	during func()
+}
+
+func (s *revokedTokensStore) RevokeToken(context.Context, string, int, time.Time) error {
+	return nil
+}
+
+func (s *revokedTokensStore) ListRevokedTokens(context.Context) ([]repository.RevokedToken, error) {
+	if s.during != nil {
+		s.during()
+	}
+
+	return s.tokens, nil
+}
+
+func TestRevocationListLoadKeepsConcurrentRevocations(t *testing.T) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	store := &revokedTokensStore{
+		tokens: []repository.RevokedToken{{Jti: "other-instance", UserId: 2, ExpiresAt: expiresAt}},
+	}
+	rl := NewRevocationList(store)
+	store.during = func() {
+		// Revoked after the query read the table, before the cache is updated
+		if err := rl.Revoke(ctx, "during-load", 1, expiresAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := rl.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, jti := range []string{"during-load", "other-instance"} {
+		if !rl.IsRevoked(jti) {
+			t.Errorf("token %q is not revoked after the load", jti)
+		}
+	}
+}
+
+func TestRevocationListLoadDropsExpired(t *testing.T) {
+	ctx := context.Background()
+	rl := NewRevocationList(&revokedTokensStore{})
+
+	if err := rl.Revoke(ctx, "expired", 1, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rl.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if rl.IsRevoked("expired") {
+		t.Error("expired token is still in the cache after the load")
+	}
+}
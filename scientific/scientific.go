@@ -0,0 +1,337 @@
+// Package scientific implements the scientific functions of the calculator:
+// powers and roots, exponential and logarithms, trigonometry and rounding.
+//
+// Every function checks that its inputs belong to its domain and reports the
+// ones that do not as a *DomainError, whose Code tells clients why without
+// parsing the message. A result that overflows float64 is reported the same
+// way, with CodeNotFinite.
+package scientific
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// MaxDigits bounds the number of decimals Round accepts, float64 holds about
+// 15 significant digits.
+const MaxDigits = 15
+
+// Codes of the domain errors.
+const (
+	// CodeNegativeRoot is the square root, or a root of even degree, of a
+	// negative number
+	CodeNegativeRoot = "negative_root"
+	// CodeNegativeBase is a negative number raised to a fractional power
+	CodeNegativeBase = "negative_base"
+	// CodeZeroDivision is zero raised to a negative power, or a root of
+	// negative degree of zero
+	CodeZeroDivision = "zero_division"
+	// CodeInvalidDegree is a root of degree zero
+	CodeInvalidDegree = "invalid_degree"
+	// CodeNonPositiveLog is the logarithm of zero or of a negative number
+	CodeNonPositiveLog = "non_positive_log"
+	// CodeInvalidLogBase is a logarithm in base 1 or a non-positive base
+	CodeInvalidLogBase = "invalid_log_base"
+	// CodeOutOfRange is the arc sine or arc cosine of a number outside of
+	// [-1, 1]
+	CodeOutOfRange = "out_of_range"
+	// CodeUndefinedTangent is the tangent of an odd multiple of 90 degrees
+	CodeUndefinedTangent = "undefined_tangent"
+	// CodeInvalidDigits is a number of decimals outside of
+	// [-MaxDigits, MaxDigits]
+	CodeInvalidDigits = "invalid_digits"
+	// CodeNotFinite is a result too large for float64
+	CodeNotFinite = "not_finite"
+)
+
+// DomainError is returned when the inputs of a function are outside of its
+// domain.
+type DomainError struct {
+	Code     string    `json:"-"`
+	Function string    `json:"function"`
+	Inputs   []float64 `json:"inputs"`
+	Reason   string    `json:"-"`
+}
+
+func (e *DomainError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Function, e.Reason)
+}
+
+func domainError(code, function, reason string, inputs ...float64) error {
+	return &DomainError{Code: code, Function: function, Inputs: inputs, Reason: reason}
+}
+
+// finite reports a result that overflowed as a CodeNotFinite error.
+func finite(function string, result float64, inputs ...float64) (float64, error) {
+	if math.IsInf(result, 0) || math.IsNaN(result) {
+		return 0, domainError(CodeNotFinite, function, "result is not a finite number", inputs...)
+	}
+
+	return result, nil
+}
+
+// Unit is the unit of the angles given to or returned by the trigonometric
+// functions.
+type Unit string
+
+const (
+	Radians Unit = "radians"
+	Degrees Unit = "degrees"
+)
+
+var ErrInvalidUnit = errors.New("angle unit should be radians or degrees")
+
+// ParseUnit returns the unit named s, radians when s is empty.
+func ParseUnit(s string) (Unit, error) {
+	switch Unit(s) {
+	case "", Radians:
+		return Radians, nil
+	case Degrees:
+		return Degrees, nil
+	default:
+		return "", ErrInvalidUnit
+	}
+}
+
+// Pow returns base raised to exponent.
+func Pow(base, exponent float64) (float64, error) {
+	switch {
+	case base == 0 && exponent < 0:
+		return 0, domainError(CodeZeroDivision, "pow", "zero cannot be raised to a negative power", base, exponent)
+	case base < 0 && exponent != math.Trunc(exponent):
+		return 0, domainError(CodeNegativeBase, "pow", "a negative number cannot be raised to a fractional power", base, exponent)
+	}
+
+	return finite("pow", math.Pow(base, exponent), base, exponent)
+}
+
+// Sqrt returns the square root of x.
+func Sqrt(x float64) (float64, error) {
+	if x < 0 {
+		return 0, domainError(CodeNegativeRoot, "sqrt", "the square root of a negative number is not real", x)
+	}
+
+	return math.Sqrt(x), nil
+}
+
+// Root returns the root of degree n of x. Odd roots of negative numbers are
+// real, e.g. the cube root of -8 is -2.
+func Root(x float64, n int) (float64, error) {
+	fn := float64(n)
+	switch {
+	case n == 0:
+		return 0, domainError(CodeInvalidDegree, "root", "the degree of a root should not be zero", x, fn)
+	case x < 0 && n%2 == 0:
+		return 0, domainError(CodeNegativeRoot, "root", "an even root of a negative number is not real", x, fn)
+	case x == 0 && n < 0:
+		return 0, domainError(CodeZeroDivision, "root", "zero has no root of negative degree", x, fn)
+	}
+
+	var result float64
+	switch n {
+	case 2:
+		result = math.Sqrt(x)
+	case 3:
+		result = math.Cbrt(x)
+	default:
+		result = math.Pow(math.Abs(x), 1/fn)
+		if x < 0 {
+			result = -result
+		}
+	}
+
+	return finite("root", result, x, fn)
+}
+
+// Exp returns e raised to x.
+func Exp(x float64) (float64, error) {
+	return finite("exp", math.Exp(x), x)
+}
+
+// Ln returns the natural logarithm of x.
+func Ln(x float64) (float64, error) {
+	if x <= 0 {
+		return 0, domainError(CodeNonPositiveLog, "ln", "the logarithm is only defined for positive numbers", x)
+	}
+
+	return math.Log(x), nil
+}
+
+// Log10 returns the decimal logarithm of x.
+func Log10(x float64) (float64, error) {
+	if x <= 0 {
+		return 0, domainError(CodeNonPositiveLog, "log10", "the logarithm is only defined for positive numbers", x)
+	}
+
+	return math.Log10(x), nil
+}
+
+// Log returns the logarithm of x in base.
+func Log(x, base float64) (float64, error) {
+	switch {
+	case x <= 0:
+		return 0, domainError(CodeNonPositiveLog, "log", "the logarithm is only defined for positive numbers", x, base)
+	case base <= 0 || base == 1:
+		return 0, domainError(CodeInvalidLogBase, "log", "the base of a logarithm should be positive and different from 1", x, base)
+	}
+
+	// Exact for the powers of 2 and 10, which log(x)/log(base) is not
+	switch base {
+	case 2:
+		return math.Log2(x), nil
+	case 10:
+		return math.Log10(x), nil
+	}
+
+	return math.Log(x) / math.Log(base), nil
+}
+
+// quarterTurns returns how many quarter turns the angle x in degrees is, when
+// it is a multiple of 90 degrees. The trigonometric functions of these angles
+// are exact, so that sin(180°) is 0 rather than 1.2e-16.
+func quarterTurns(x float64, unit Unit) (int, bool) {
+	if unit != Degrees || math.Mod(x, 90) != 0 {
+		return 0, false
+	}
+
+	turns := int(math.Mod(x/90, 4))
+	if turns < 0 {
+		turns += 4
+	}
+
+	return turns, true
+}
+
+func toRadians(x float64, unit Unit) float64 {
+	if unit == Degrees {
+		// Reduced first, a large angle loses less precision in degrees
+		return math.Mod(x, 360) * math.Pi / 180
+	}
+
+	return x
+}
+
+func fromRadians(x float64, unit Unit) float64 {
+	if unit == Degrees {
+		return x * 180 / math.Pi
+	}
+
+	return x
+}
+
+// Sin returns the sine of the angle x.
+func Sin(x float64, unit Unit) (float64, error) {
+	if turns, ok := quarterTurns(x, unit); ok {
+		return [4]float64{0, 1, 0, -1}[turns], nil
+	}
+
+	return math.Sin(toRadians(x, unit)), nil
+}
+
+// Cos returns the cosine of the angle x.
+func Cos(x float64, unit Unit) (float64, error) {
+	if turns, ok := quarterTurns(x, unit); ok {
+		return [4]float64{1, 0, -1, 0}[turns], nil
+	}
+
+	return math.Cos(toRadians(x, unit)), nil
+}
+
+// Tan returns the tangent of the angle x. In degrees, the tangent of an odd
+// multiple of 90 is undefined; in radians these angles cannot be represented
+// exactly and the result is merely very large.
+func Tan(x float64, unit Unit) (float64, error) {
+	if turns, ok := quarterTurns(x, unit); ok {
+		if turns%2 == 1 {
+			return 0, domainError(CodeUndefinedTangent, "tan", "the tangent of an odd multiple of 90 degrees is undefined", x)
+		}
+		return 0, nil
+	}
+
+	return math.Tan(toRadians(x, unit)), nil
+}
+
+// Asin returns the arc sine of x, as an angle in unit.
+func Asin(x float64, unit Unit) (float64, error) {
+	if x < -1 || x > 1 {
+		return 0, domainError(CodeOutOfRange, "asin", "the arc sine is only defined between -1 and 1", x)
+	}
+
+	return fromRadians(math.Asin(x), unit), nil
+}
+
+// Acos returns the arc cosine of x, as an angle in unit.
+func Acos(x float64, unit Unit) (float64, error) {
+	if x < -1 || x > 1 {
+		return 0, domainError(CodeOutOfRange, "acos", "the arc cosine is only defined between -1 and 1", x)
+	}
+
+	return fromRadians(math.Acos(x), unit), nil
+}
+
+// Atan returns the arc tangent of x, as an angle in unit.
+func Atan(x float64, unit Unit) (float64, error) {
+	return fromRadians(math.Atan(x), unit), nil
+}
+
+// Abs returns the absolute value of x.
+func Abs(x float64) (float64, error) {
+	return math.Abs(x), nil
+}
+
+// Floor returns the greatest integer lower than or equal to x.
+func Floor(x float64) (float64, error) {
+	return math.Floor(x), nil
+}
+
+// Ceil returns the least integer greater than or equal to x.
+func Ceil(x float64) (float64, error) {
+	return math.Ceil(x), nil
+}
+
+// Round rounds x half away from zero to digits decimals. A negative number of
+// digits rounds to the left of the decimal point, e.g. Round(1234, -2) is
+// 1200. x is rounded as written in decimal, so that Round(1.005, 2) is 1.01
+// even though 1.005 is slightly less in binary.
+func Round(x float64, digits int) (float64, error) {
+	if digits < -MaxDigits || digits > MaxDigits {
+		return 0, domainError(CodeInvalidDigits, "round", fmt.Sprintf("digits should be between -%d and %d", MaxDigits, MaxDigits), x, float64(digits))
+	}
+
+	// The shortest decimal text of a float64 is always a valid big.Rat
+	scaled, _ := new(big.Rat).SetString(strconv.FormatFloat(x, 'g', -1, 64))
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(digits))), nil))
+	if digits >= 0 {
+		scaled.Mul(scaled, scale)
+	} else {
+		scaled.Quo(scaled, scale)
+	}
+
+	// Truncate toward zero, then round away when the remainder is at least
+	// half of the denominator
+	n, rem := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if rem.Abs(rem).Lsh(rem, 1).Cmp(scaled.Denom()) >= 0 {
+		n.Add(n, big.NewInt(int64(scaled.Sign())))
+	}
+
+	rounded := new(big.Rat).SetInt(n)
+	if digits >= 0 {
+		rounded.Quo(rounded, scale)
+	} else {
+		rounded.Mul(rounded, scale)
+	}
+	result, _ := rounded.Float64()
+
+	return result, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
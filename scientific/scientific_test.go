@@ -0,0 +1,196 @@
+package scientific
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestFunctions(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       func() (float64, error)
+		expected float64
+	}{
+		{"pow(2, 10)", func() (float64, error) { return Pow(2, 10) }, 1024},
+		{"pow(2, -1)", func() (float64, error) { return Pow(2, -1) }, 0.5},
+		{"pow(-2, 3)", func() (float64, error) { return Pow(-2, 3) }, -8},
+		{"pow(0, 0)", func() (float64, error) { return Pow(0, 0) }, 1},
+		{"sqrt(16)", func() (float64, error) { return Sqrt(16) }, 4},
+		{"sqrt(0)", func() (float64, error) { return Sqrt(0) }, 0},
+		{"root(27, 3)", func() (float64, error) { return Root(27, 3) }, 3},
+		{"root(-8, 3)", func() (float64, error) { return Root(-8, 3) }, -2},
+		{"root(-32, 5)", func() (float64, error) { return Root(-32, 5) }, -2},
+		{"root(16, 2)", func() (float64, error) { return Root(16, 2) }, 4},
+		{"root(4, -2)", func() (float64, error) { return Root(4, -2) }, 0.5},
+		{"exp(0)", func() (float64, error) { return Exp(0) }, 1},
+		{"ln(1)", func() (float64, error) { return Ln(1) }, 0},
+		{"log10(1000)", func() (float64, error) { return Log10(1000) }, 3},
+		{"log(8, 2)", func() (float64, error) { return Log(8, 2) }, 3},
+		{"log(1e-3, 10)", func() (float64, error) { return Log(1e-3, 10) }, -3},
+		{"log(81, 3)", func() (float64, error) { return Log(81, 3) }, 4},
+		{"abs(-2.5)", func() (float64, error) { return Abs(-2.5) }, 2.5},
+		{"floor(-2.5)", func() (float64, error) { return Floor(-2.5) }, -3},
+		{"ceil(-2.5)", func() (float64, error) { return Ceil(-2.5) }, -2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.fn()
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if math.Abs(got-tt.expected) > 1e-12 {
+				t.Errorf("got %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestTrigonometryExact checks that the multiples of 90 degrees give exact
+// results, not 1.2e-16.
+func TestTrigonometryExact(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       func(float64, Unit) (float64, error)
+		x        float64
+		expected float64
+	}{
+		{"sin", Sin, 0, 0},
+		{"sin", Sin, 90, 1},
+		{"sin", Sin, 180, 0},
+		{"sin", Sin, 270, -1},
+		{"sin", Sin, 360, 0},
+		{"sin", Sin, -90, -1},
+		{"sin", Sin, 540, 0},
+		{"cos", Cos, 90, 0},
+		{"cos", Cos, 180, -1},
+		{"cos", Cos, -180, -1},
+		{"cos", Cos, 720, 1},
+		{"tan", Tan, 180, 0},
+		{"tan", Tan, -360, 0},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.fn(tt.x, Degrees)
+		if err != nil {
+			t.Errorf("%s(%v°): unexpected error %v", tt.name, tt.x, err)
+		} else if got != tt.expected {
+			t.Errorf("%s(%v°) = %v, expected exactly %v", tt.name, tt.x, got, tt.expected)
+		}
+	}
+}
+
+func TestTrigonometry(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       func(float64, Unit) (float64, error)
+		x        float64
+		unit     Unit
+		expected float64
+	}{
+		{"sin", Sin, math.Pi / 6, Radians, 0.5},
+		{"sin", Sin, 30, Degrees, 0.5},
+		{"cos", Cos, 60, Degrees, 0.5},
+		{"tan", Tan, 45, Degrees, 1},
+		{"asin", Asin, 1, Degrees, 90},
+		{"asin", Asin, 1, Radians, math.Pi / 2},
+		{"acos", Acos, -1, Degrees, 180},
+		{"atan", Atan, 1, Degrees, 45},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.fn(tt.x, tt.unit)
+		if err != nil {
+			t.Errorf("%s(%v %s): unexpected error %v", tt.name, tt.x, tt.unit, err)
+		} else if math.Abs(got-tt.expected) > 1e-12 {
+			t.Errorf("%s(%v %s) = %v, expected %v", tt.name, tt.x, tt.unit, got, tt.expected)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		x        float64
+		digits   int
+		expected float64
+	}{
+		// 1.005 is 1.00499999999999989... in binary, it is rounded as written
+		{1.005, 2, 1.01},
+		{2.675, 2, 2.68},
+		{-1.005, 2, -1.01},
+		{2.5, 0, 3},
+		{-2.5, 0, -3},
+		{0.5, 0, 1},
+		{1.4999, 0, 1},
+		{3.14159, 3, 3.142},
+		{1234, -2, 1200},
+		{1250, -2, 1300},
+		{-1250, -2, -1300},
+		{49, -2, 0},
+		{0.1, 15, 0.1},
+	}
+
+	for _, tt := range tests {
+		got, err := Round(tt.x, tt.digits)
+		if err != nil {
+			t.Errorf("Round(%v, %d): unexpected error %v", tt.x, tt.digits, err)
+		} else if got != tt.expected {
+			t.Errorf("Round(%v, %d) = %v, expected %v", tt.x, tt.digits, got, tt.expected)
+		}
+	}
+}
+
+func TestDomainErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func() (float64, error)
+		code string
+	}{
+		{"pow(0, -1)", func() (float64, error) { return Pow(0, -1) }, CodeZeroDivision},
+		{"pow(-8, 0.5)", func() (float64, error) { return Pow(-8, 0.5) }, CodeNegativeBase},
+		{"pow(10, 400)", func() (float64, error) { return Pow(10, 400) }, CodeNotFinite},
+		{"sqrt(-1)", func() (float64, error) { return Sqrt(-1) }, CodeNegativeRoot},
+		{"root(8, 0)", func() (float64, error) { return Root(8, 0) }, CodeInvalidDegree},
+		{"root(-16, 4)", func() (float64, error) { return Root(-16, 4) }, CodeNegativeRoot},
+		{"root(0, -2)", func() (float64, error) { return Root(0, -2) }, CodeZeroDivision},
+		{"exp(1000)", func() (float64, error) { return Exp(1000) }, CodeNotFinite},
+		{"ln(0)", func() (float64, error) { return Ln(0) }, CodeNonPositiveLog},
+		{"log10(-1)", func() (float64, error) { return Log10(-1) }, CodeNonPositiveLog},
+		{"log(-8, 2)", func() (float64, error) { return Log(-8, 2) }, CodeNonPositiveLog},
+		{"log(8, 1)", func() (float64, error) { return Log(8, 1) }, CodeInvalidLogBase},
+		{"log(8, -2)", func() (float64, error) { return Log(8, -2) }, CodeInvalidLogBase},
+		{"tan(90°)", func() (float64, error) { return Tan(90, Degrees) }, CodeUndefinedTangent},
+		{"tan(-270°)", func() (float64, error) { return Tan(-270, Degrees) }, CodeUndefinedTangent},
+		{"asin(2)", func() (float64, error) { return Asin(2, Radians) }, CodeOutOfRange},
+		{"acos(-1.5)", func() (float64, error) { return Acos(-1.5, Degrees) }, CodeOutOfRange},
+		{"round(1, 16)", func() (float64, error) { return Round(1, MaxDigits+1) }, CodeInvalidDigits},
+		{"round(1, -16)", func() (float64, error) { return Round(1, -MaxDigits-1) }, CodeInvalidDigits},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.fn()
+
+			var domainErr *DomainError
+			if !errors.As(err, &domainErr) {
+				t.Fatalf("got error %v, expected a *DomainError", err)
+			}
+			if domainErr.Code != tt.code {
+				t.Errorf("got code %q, expected %q", domainErr.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestParseUnit(t *testing.T) {
+	for input, expected := range map[string]Unit{"": Radians, "radians": Radians, "degrees": Degrees} {
+		if got, err := ParseUnit(input); err != nil || got != expected {
+			t.Errorf("ParseUnit(%q) = %q, %v, expected %q", input, got, err, expected)
+		}
+	}
+
+	if _, err := ParseUnit("grads"); !errors.Is(err, ErrInvalidUnit) {
+		t.Errorf("ParseUnit(grads): got error %v, expected %v", err, ErrInvalidUnit)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/NDOY3M4N/api-calculator/repository"
+)
+
+// Scopes name the actions a credential is allowed to perform.
+const (
+	ScopeMathAdd       = "math:add"
+	ScopeMathSum       = "math:sum"
+	ScopeMathSubstract = "math:substract"
+	ScopeMathMultiply  = "math:multiply"
+	ScopeMathDivide    = "math:divide"
+	ScopeMathEvaluate  = "math:evaluate"
+	// ScopeMathScientific covers all the scientific functions
+	ScopeMathScientific = "math:scientific"
+	ScopeMathStats      = "math:stats"
+	ScopeMathLinalg     = "math:linalg"
+	ScopeMathComplex    = "math:complex"
+	ScopeHistoryRead    = "history:read"
+	ScopeKeysManage     = "keys:manage"
+	ScopeUsersAdmin     = "users:admin"
+)
+
+var knownScopes = []string{
+	ScopeMathAdd,
+	ScopeMathSum,
+	ScopeMathSubstract,
+	ScopeMathMultiply,
+	ScopeMathDivide,
+	ScopeMathEvaluate,
+	ScopeMathScientific,
+	ScopeMathStats,
+	ScopeMathLinalg,
+	ScopeMathComplex,
+	ScopeHistoryRead,
+	ScopeKeysManage,
+	ScopeUsersAdmin,
+}
+
+// roleScopes lists the scopes granted to each role. API keys are further
+// restricted to the scopes chosen at creation.
+var roleScopes = map[repository.Role][]string{
+	repository.RoleAdmin: knownScopes,
+	repository.RoleUser: {
+		ScopeMathAdd,
+		ScopeMathSum,
+		ScopeMathSubstract,
+		ScopeMathMultiply,
+		ScopeMathDivide,
+		ScopeMathEvaluate,
+		ScopeMathScientific,
+		ScopeMathStats,
+		ScopeMathLinalg,
+		ScopeMathComplex,
+		ScopeHistoryRead,
+		ScopeKeysManage,
+	},
+	repository.RoleReadOnly: {
+		ScopeHistoryRead,
+	},
+}
+
+var (
+	ErrMissingScopes = errors.New("provide at least one scope")
+	ErrInvalidRole   = errors.New("role should be admin, user or read-only")
+)
+
+// MissingScopeError is returned when a credential lacks the scope required by
+// a route.
+type MissingScopeError struct {
+	Scope string `json:"missing_scope"`
+}
+
+func (e *MissingScopeError) Error() string {
+	return fmt.Sprintf("missing scope %s", e.Scope)
+}
+
+func scopesForRole(role repository.Role) []string {
+	return roleScopes[role]
+}
+
+func validateRole(role repository.Role) error {
+	if _, ok := roleScopes[role]; !ok {
+		return ErrInvalidRole
+	}
+
+	return nil
+}
+
+// normalizeScopes checks that every scope is known and removes duplicates.
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, ErrMissingScopes
+	}
+
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !slices.Contains(knownScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !slices.Contains(normalized, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+
+	return normalized, nil
+}
+
+// intersectScopes returns the scopes of a that are also in b.
+func intersectScopes(a, b []string) []string {
+	scopes := make([]string, 0, len(a))
+	for _, scope := range a {
+		if slices.Contains(b, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return scopes
+}
@@ -0,0 +1,182 @@
+// Package stats computes the descriptive statistics of a series of numbers.
+//
+// The sum is compensated (Neumaier's variant of Kahan summation) and the mean
+// and variance are updated with Welford's algorithm, so that long series, or
+// series of large and close numbers, do not accumulate rounding errors.
+package stats
+
+import (
+	"errors"
+	"math"
+	"slices"
+)
+
+var (
+	ErrEmpty           = errors.New("provide at least one number")
+	ErrPercentileRange = errors.New("percentiles should be between 0 and 100")
+)
+
+// Spread is a variance or a standard deviation, computed both for a sample
+// and for a whole population. Either is null when it is too large for a
+// float64, e.g. the variance of [1e308, -1e308].
+type Spread struct {
+	// Sample is null for a single number
+	Sample     *float64 `json:"sample"`
+	Population *float64 `json:"population"`
+}
+
+type Percentile struct {
+	Percentile float64 `json:"percentile" example:"90"`
+	Value      float64 `json:"value" example:"3.6"`
+}
+
+// Summary holds the descriptive statistics of a series of numbers.
+type Summary struct {
+	Count int `json:"count" example:"5"`
+	// Sum is null when it is too large for a float64, like the spreads; the
+	// other statistics lie between the smallest and largest numbers
+	Sum    *float64 `json:"sum" example:"12"`
+	Min    float64  `json:"min" example:"1"`
+	Max    float64  `json:"max" example:"4"`
+	Mean   float64  `json:"mean" example:"2.4"`
+	Median float64  `json:"median" example:"2"`
+	// Mode lists the most frequent numbers, it is empty when they all appear
+	// once
+	Mode     []float64 `json:"mode"`
+	Variance Spread    `json:"variance"`
+	StdDev   Spread    `json:"std_dev"`
+	// Percentiles are interpolated between the closest ranks, in the order
+	// they were requested
+	Percentiles []Percentile `json:"percentiles,omitempty"`
+}
+
+// Summarize returns the statistics of xs and its percentiles, each between 0
+// and 100.
+func Summarize(xs []float64, percentiles ...float64) (*Summary, error) {
+	if len(xs) == 0 {
+		return nil, ErrEmpty
+	}
+	for _, p := range percentiles {
+		if p < 0 || p > 100 {
+			return nil, ErrPercentileRange
+		}
+	}
+
+	sorted := slices.Clone(xs)
+	slices.Sort(sorted)
+
+	// A power of two, so that scaling the numbers by it is exact, above half
+	// their largest magnitude
+	_, exp := math.Frexp(max(-sorted[0], sorted[len(sorted)-1]))
+	scale := math.Ldexp(1, exp-1)
+
+	mean, m2 := welford(xs, scale)
+	n := float64(len(xs))
+
+	s := &Summary{
+		Count:  len(xs),
+		Sum:    finite(Sum(xs)),
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+		Mean:   mean,
+		Median: percentile(sorted, 50),
+		Mode:   mode(sorted),
+	}
+
+	s.Variance.Population = finite(m2 / n * scale * scale)
+	s.StdDev.Population = finite(math.Sqrt(m2/n) * scale)
+	if len(xs) > 1 {
+		s.Variance.Sample = finite(m2 / (n - 1) * scale * scale)
+		s.StdDev.Sample = finite(math.Sqrt(m2/(n-1)) * scale)
+	}
+
+	for _, p := range percentiles {
+		s.Percentiles = append(s.Percentiles, Percentile{p, percentile(sorted, p)})
+	}
+
+	return s, nil
+}
+
+// finite returns x, or nil when it overflowed.
+func finite(x float64) *float64 {
+	if math.IsInf(x, 0) || math.IsNaN(x) {
+		return nil
+	}
+
+	return &x
+}
+
+// Sum returns the sum of xs, compensated for the rounding error of each
+// addition.
+func Sum(xs []float64) float64 {
+	var sum, compensation float64
+	for _, x := range xs {
+		t := sum + x
+		// The low-order bits are lost from the smaller of the two operands
+		if math.Abs(sum) >= math.Abs(x) {
+			compensation += (sum - t) + x
+		} else {
+			compensation += (x - t) + sum
+		}
+		sum = t
+	}
+
+	return sum + compensation
+}
+
+// welford returns the mean of xs and the sum of the squared differences from
+// it, updated one number at a time. The numbers are divided by scale, at
+// least half their largest magnitude, so that the differences cannot
+// overflow; m2 is returned at that scale.
+func welford(xs []float64, scale float64) (mean, m2 float64) {
+	for i, x := range xs {
+		x /= scale
+		delta := x - mean
+		mean += delta / float64(i+1)
+		m2 += delta * (x - mean)
+	}
+
+	return mean * scale, m2
+}
+
+// percentile interpolates linearly between the closest ranks of sorted, as
+// the PERCENTILE.INC function of spreadsheets.
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	if lo == hi {
+		return sorted[lo]
+	}
+
+	f := rank - float64(lo)
+	if diff := sorted[hi] - sorted[lo]; !math.IsInf(diff, 0) {
+		return sorted[lo] + f*diff
+	}
+
+	// The weighted form loses precision but cannot overflow
+	return sorted[lo]*(1-f) + sorted[hi]*f
+}
+
+// mode returns the most frequent numbers of sorted, in increasing order.
+func mode(sorted []float64) []float64 {
+	modes := []float64{}
+	best := 1
+	for i := 0; i < len(sorted); {
+		j := i + 1
+		for j < len(sorted) && sorted[j] == sorted[i] {
+			j++
+		}
+
+		switch count := j - i; {
+		case count > best:
+			best = count
+			modes = append(modes[:0], sorted[i])
+		case count == best && best > 1:
+			modes = append(modes, sorted[i])
+		}
+		i = j
+	}
+
+	return modes
+}
@@ -0,0 +1,198 @@
+package stats
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	s, err := Summarize([]float64{4, 2, 1, 3, 2}, 25, 90, 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Count != 5 || s.Sum == nil || *s.Sum != 12 || s.Min != 1 || s.Max != 4 || s.Mean != 2.4 || s.Median != 2 {
+		t.Errorf("got %+v", s)
+	}
+	if !slices.Equal(s.Mode, []float64{2}) {
+		t.Errorf("got mode %v, expected [2]", s.Mode)
+	}
+	if !spread(s.Variance, 1.3, 1.04) {
+		t.Errorf("got variance %+v, expected 1.3 and 1.04", s.Variance)
+	}
+	if !spread(s.StdDev, math.Sqrt(1.3), math.Sqrt(1.04)) {
+		t.Errorf("got standard deviation %+v", s.StdDev)
+	}
+
+	expected := []Percentile{{25, 2}, {90, 3.6}, {0, 1}, {100, 4}}
+	if len(s.Percentiles) != len(expected) {
+		t.Fatalf("got percentiles %v, expected %v", s.Percentiles, expected)
+	}
+	for i, p := range s.Percentiles {
+		if p.Percentile != expected[i].Percentile || !near(p.Value, expected[i].Value) {
+			t.Errorf("got percentile %v, expected %v", p, expected[i])
+		}
+	}
+}
+
+func TestSummarizeSingleNumber(t *testing.T) {
+	s, err := Summarize([]float64{7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Mean != 7 || s.Median != 7 || s.Variance.Population == nil || *s.Variance.Population != 0 || s.StdDev.Population == nil || *s.StdDev.Population != 0 {
+		t.Errorf("got %+v", s)
+	}
+	if s.Variance.Sample != nil || s.StdDev.Sample != nil {
+		t.Errorf("got sample spreads %v and %v, expected none for a single number", s.Variance.Sample, s.StdDev.Sample)
+	}
+	if len(s.Mode) != 0 {
+		t.Errorf("got mode %v, expected none", s.Mode)
+	}
+}
+
+// TestSummarizeLargeCloseValues checks the series where the naive formulas
+// lose every significant digit: the sum of squares of numbers around 1e9
+// cancels out in float64.
+func TestSummarizeLargeCloseValues(t *testing.T) {
+	s, err := Summarize([]float64{1e9 + 4, 1e9 + 7, 1e9 + 13, 1e9 + 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Mean != 1e9+10 {
+		t.Errorf("got mean %v, expected %v", s.Mean, 1e9+10)
+	}
+	if s.Variance.Sample == nil || *s.Variance.Sample != 30 || s.Variance.Population == nil || *s.Variance.Population != 22.5 {
+		t.Errorf("got variance %+v, expected 30 and 22.5", s.Variance)
+	}
+}
+
+// TestSummarizeOverflow checks that the differences of huge numbers do not
+// overflow, and that only the spreads too large for a float64 are left out:
+// the variances of [1e308, -1e308] are above 1e616.
+func TestSummarizeOverflow(t *testing.T) {
+	s, err := Summarize([]float64{1e308, -1e308})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Sum == nil || *s.Sum != 0 || s.Mean != 0 || s.Median != 0 {
+		t.Errorf("got %+v", s)
+	}
+	if s.Variance.Sample != nil || s.Variance.Population != nil {
+		t.Errorf("got variance %+v, expected none", s.Variance)
+	}
+	if !spread(s.StdDev, math.Sqrt2*1e308, 1e308) {
+		t.Errorf("got standard deviation %+v, expected %v and 1e308", s.StdDev, math.Sqrt2*1e308)
+	}
+
+	s, err = Summarize([]float64{math.MaxFloat64, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Mean != math.MaxFloat64/2 || !spread(s.StdDev, math.MaxFloat64/math.Sqrt2, math.MaxFloat64/2) {
+		t.Errorf("got mean %v and standard deviation %+v", s.Mean, s.StdDev)
+	}
+}
+
+// TestSummarizeSumOverflow checks that a sum too large for a float64 is left
+// out on its own, the other statistics of the numbers being finite.
+func TestSummarizeSumOverflow(t *testing.T) {
+	for _, xs := range [][]float64{{1e308, 1e308, 1}, {math.MaxFloat64, math.MaxFloat64}} {
+		s, err := Summarize(xs, 50)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s.Sum != nil {
+			t.Errorf("got sum %v of %v, expected none", *s.Sum, xs)
+		}
+		if s.Count != len(xs) || s.Max != xs[0] || s.Median != xs[1] || s.Percentiles[0].Value != xs[1] {
+			t.Errorf("got %+v", s)
+		}
+		if math.IsInf(s.Mean, 0) || s.Mean <= 0 || s.Mean > s.Max {
+			t.Errorf("got mean %v of %v", s.Mean, xs)
+		}
+		if s.StdDev.Sample == nil || s.StdDev.Population == nil {
+			t.Errorf("got standard deviation %+v of %v", s.StdDev, xs)
+		}
+	}
+}
+
+func TestSum(t *testing.T) {
+	tests := []struct {
+		name     string
+		xs       []float64
+		expected float64
+	}{
+		{"empty", nil, 0},
+		{"integers", []float64{1, 2, 3}, 6},
+		// The 1s are lost by a naive sum next to 1e100
+		{"cancellation", []float64{1, 1e100, 1, -1e100}, 2},
+		{"small after large", []float64{1e16, 1, 1, 1, 1}, 1e16 + 4},
+		{"tenths", slices.Repeat([]float64{0.1}, 10), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sum(tt.xs); got != tt.expected {
+				t.Errorf("Sum(%v) = %v, expected %v", tt.xs, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMode(t *testing.T) {
+	tests := []struct {
+		xs       []float64
+		expected []float64
+	}{
+		{[]float64{1, 2, 3}, []float64{}},
+		{[]float64{1, 1, 2, 3}, []float64{1}},
+		{[]float64{3, 1, 3, 1, 2}, []float64{1, 3}},
+		{[]float64{5, 5, 5}, []float64{5}},
+	}
+
+	for _, tt := range tests {
+		s, err := Summarize(tt.xs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(s.Mode, tt.expected) {
+			t.Errorf("mode of %v = %v, expected %v", tt.xs, s.Mode, tt.expected)
+		}
+	}
+}
+
+func TestSummarizeErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		xs          []float64
+		percentiles []float64
+		expected    error
+	}{
+		{"empty", nil, nil, ErrEmpty},
+		{"negative percentile", []float64{1}, []float64{-1}, ErrPercentileRange},
+		{"percentile above 100", []float64{1}, []float64{100.5}, ErrPercentileRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Summarize(tt.xs, tt.percentiles...); !errors.Is(err, tt.expected) {
+				t.Errorf("got error %v, expected %v", err, tt.expected)
+			}
+		})
+	}
+}
+
+func spread(s Spread, sample, population float64) bool {
+	return s.Sample != nil && s.Population != nil && near(*s.Sample, sample) && near(*s.Population, population)
+}
+
+func near(got, expected float64) bool {
+	return math.Abs(got-expected) <= 1e-12*math.Max(1, math.Abs(expected))
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const serviceName = "api-calculator"
+
+// tracer uses the global provider, so the spans started before setupTracing
+// or when tracing is disabled are no-ops.
+var tracer = otel.Tracer("github.com/NDOY3M4N/api-calculator")
+
+// setupTracing installs the exporter selected by conf.Tracing.Exporter: "otlp"
+// sends the spans to a collector (OTEL_EXPORTER_OTLP_ENDPOINT, by default
+// http://localhost:4318), "stdout" prints them, "none" disables tracing. The
+// returned function flushes the pending spans.
+func setupTracing(ctx context.Context) (func(context.Context) error, error) {
+	// traceparent is honored even when the spans are not exported, so that
+	// the trace IDs in the logs match the caller's
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch conf.Tracing.Exporter {
+	case "none", "":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown traces exporter %q, expected otlp, stdout or none", conf.Tracing.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Tracing starts the server span of a request, continuing the trace of the
+// caller when the request has a traceparent header. It should come first in
+// the stack so that the other middlewares are part of the trace.
+func Tracing(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.UserAgentOriginal(r.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		r, info := withRequestInfo(r.WithContext(ctx))
+		wrappedWritter := newWrapperWritter(w)
+		next.ServeHTTP(wrappedWritter, r)
+
+		if info.route != "" {
+			span.SetName(r.Method + " " + info.route)
+			span.SetAttributes(semconv.HTTPRoute(info.route))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(wrappedWritter.statusCode))
+		if wrappedWritter.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(wrappedWritter.statusCode))
+		}
+	}
+}
+
+// Traced wraps m in a span named name, which covers the time spent in m and
+// in the rest of the stack.
+func Traced(name string, m Middleware) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		h := m(next)
+
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx, span := tracer.Start(r.Context(), "middleware "+name)
+			defer span.End()
+
+			h(w, r.WithContext(ctx))
+		}
+	}
+}
+
+// TraceHandler starts the span of the handler matched by the router. It
+// should be the last middleware, right before the handler.
+func TraceHandler(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracer.Start(r.Context(), "handler "+r.Pattern)
+		defer span.End()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// traceLogHandler adds the trace and span IDs to the records logged with the
+// context of a traced request.
+type traceLogHandler struct {
+	slog.Handler
+}
+
+func (h traceLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h traceLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return traceLogHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h traceLogHandler) WithGroup(name string) slog.Handler {
+	return traceLogHandler{h.Handler.WithGroup(name)}
+}
+
+// spanError marks the span of ctx as failed with err.
+func spanError(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}